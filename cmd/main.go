@@ -2,9 +2,37 @@ package main
 
 import (
 	"buttplugosu/internal/gameplay"
+	"buttplugosu/pkg/logging"
+	"flag"
+	"os"
+	"os/signal"
 )
 
 func main() {
+	var opts gameplay.Options
+	flag.StringVar(&opts.Record, "record", "", "record all memory reads into a trace file")
+	flag.StringVar(&opts.Replay, "replay", "", "replay a trace file instead of reading osu!")
+	flag.BoolVar(&opts.Strict, "strict", false, "fail on signatures matching more than one address")
+	flag.BoolVar(&opts.Validate, "validate", false, "check every pointer against the mapped memory of osu!")
+	flag.StringVar(&opts.Definitions, "definitions", "", "read signatures and offsets from a definitions file")
+	flag.Parse()
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+
+		_ = gameplay.Close()
+		os.Exit(0)
+	}()
+
 	go gameplay.HandlePlug()
-	gameplay.Init()
+	gameplay.Init(&opts)
+
+	// Init returned, flush the trace
+	if err := gameplay.Close(); err != nil {
+		logging.Global.Error().
+			Err(err).
+			Msg("Closing failed")
+	}
 }
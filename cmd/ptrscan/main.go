@@ -0,0 +1,172 @@
+// Command ptrscan finds pointer paths from the roots of staticAddresses to
+// an address, to rediscover the offsets of a field after an osu! update.
+// Paths found in the target are checked against more snapshots to weed out
+// the ones that only work by chance.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"buttplugosu/internal/gameplay"
+	"buttplugosu/internal/target"
+	"buttplugosu/pkg/logging"
+	"buttplugosu/pkg/memory"
+)
+
+// check is a snapshot or minidump the paths are checked against, with the
+// address of the field in it.
+type check struct {
+	file    string
+	address int64
+}
+
+// checks are the -check flags.
+type checks []check
+
+func (c *checks) String() string {
+	var strs []string
+	for _, ch := range *c {
+		strs = append(strs, fmt.Sprintf("%s=0x%x", ch.file, ch.address))
+	}
+
+	return strings.Join(strs, ",")
+}
+
+func (c *checks) Set(s string) error {
+	file, address, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("%q is not file=address", s)
+	}
+
+	addr, err := strconv.ParseInt(address, 0, 64)
+	if err != nil {
+		return err
+	}
+
+	*c = append(*c, check{file: file, address: addr})
+	return nil
+}
+
+func main() {
+	var checkFlags checks
+
+	targetFlags := target.AddFlags()
+	address := flag.String("address", "", "address of the field in the target, like 0x1234ABCD")
+	depth := flag.Int("depth", 4, "most pointers a path follows")
+	offset := flag.Int64("offset", 0x800, "largest offset added to a pointer")
+	limit := flag.Int("max", 1000, "most paths searched for")
+	definitions := flag.String("definitions", "", "read signatures and variables from the definitions file the game is read with")
+	flag.Var(&checkFlags, "check", "snapshot or minidump and the field's address in it, like old.snap=0x1234ABCD, can be repeated")
+	flag.Parse()
+
+	addr, err := strconv.ParseInt(*address, 0, 64)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Invalid -address")
+	}
+
+	var defs *memory.Definitions
+	if *definitions != "" {
+		if defs, err = memory.LoadDefinitions(*definitions); err != nil {
+			logging.Global.Fatal().
+				Err(err).
+				Msg("Loading definitions failed")
+		}
+	}
+
+	process, err := targetFlags.Open()
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Opening target failed")
+	}
+
+	ctx := context.Background()
+	roots := resolveRoots(ctx, process, defs)
+
+	paths, err := memory.ScanPointerPaths(ctx, process, addr, roots, &memory.PointerScanOptions{
+		MaxDepth:  *depth,
+		MaxOffset: *offset,
+		MaxPaths:  *limit,
+		Progress: func(p memory.ScanProgress) {
+			logging.Global.Debug().
+				Int64("MiB", p.BytesScanned>>20).
+				Int64("totalMiB", p.BytesTotal>>20).
+				Msg("Collecting pointers")
+		},
+	})
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Scanning pointers failed")
+	}
+	_ = process.Close()
+
+	logging.Global.Info().
+		Int("paths", len(paths)).
+		Msg("Found pointer paths")
+
+	for _, ch := range checkFlags {
+		paths = filter(ctx, ch, defs, paths)
+	}
+
+	for _, pp := range paths {
+		fmt.Printf("`memory:\"%s\"`\n", pp)
+	}
+}
+
+// resolveRoots resolves the roots in p, failing only if there are none.
+func resolveRoots(ctx context.Context, p memory.Process, defs *memory.Definitions) map[string]int64 {
+	roots, err := gameplay.ResolveRoots(ctx, p, defs)
+	if err != nil {
+		logging.Global.Warn().
+			Err(err).
+			Msg("Resolving some roots failed")
+	}
+
+	if len(roots) == 0 {
+		logging.Global.Fatal().
+			Msg("No roots resolved")
+	}
+
+	return roots
+}
+
+// filter keeps the paths that lead to the field in the file of ch.
+func filter(ctx context.Context, ch check, defs *memory.Definitions, paths []memory.PointerPath) []memory.PointerPath {
+	var p memory.Process
+	var err error
+
+	if strings.EqualFold(filepath.Ext(ch.file), ".dmp") {
+		p, err = memory.OpenMinidump(ch.file)
+	} else {
+		p, err = memory.OpenSnapshot(ch.file)
+	}
+
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Str("file", ch.file).
+			Msg("Opening check failed")
+	}
+	defer p.Close()
+
+	if layout, err := memory.DetectLayout(p); err == nil {
+		p = memory.WithLayout(p, layout)
+	}
+
+	kept := memory.FilterPointerPaths(p, resolveRoots(ctx, p, defs), ch.address, paths)
+
+	logging.Global.Info().
+		Str("file", ch.file).
+		Int("paths", len(kept)).
+		Msg("Checked pointer paths")
+
+	return kept
+}
@@ -0,0 +1,52 @@
+// Command siggen writes a unique signature for an address found by hand, as
+// a field ready to paste into staticAddresses.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"buttplugosu/internal/target"
+	"buttplugosu/pkg/logging"
+	"buttplugosu/pkg/memory"
+)
+
+func main() {
+	targetFlags := target.AddFlags()
+	address := flag.String("address", "", "address the signature resolves to, like 0x1234ABCD")
+	name := flag.String("name", "Address", "name of the field printed")
+	flag.Parse()
+
+	addr, err := strconv.ParseInt(*address, 0, 64)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Invalid -address")
+	}
+
+	process, err := targetFlags.Open()
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Opening target failed")
+	}
+	defer process.Close()
+
+	sig, err := memory.GenerateSignature(context.Background(), process, addr, &memory.ScanOptions{
+		Progress: func(p memory.ScanProgress) {
+			logging.Global.Debug().
+				Int64("MiB", p.BytesScanned>>20).
+				Int("regions", p.RegionsDone).
+				Msg("Scanning")
+		},
+	})
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Generating signature failed")
+	}
+
+	fmt.Printf("%s int64 `sig:\"%s\"`\n", *name, sig)
+}
@@ -0,0 +1,56 @@
+// Command snapshot captures the memory of a running osu! process into a file
+// that memory.OpenSnapshot can read on any machine.
+package main
+
+import (
+	"flag"
+	"os"
+	"regexp"
+
+	"buttplugosu/pkg/logging"
+	"buttplugosu/pkg/memory"
+)
+
+func main() {
+	out := flag.String("o", "osu.snap", "output file")
+	name := flag.String("process", `.*osu!\.exe.*`, "regular expression matching the process")
+	flag.Parse()
+
+	processes, err := memory.FindProcess(regexp.MustCompile(*name), "osu!lazer", "osu!framework")
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Finding process failed")
+	}
+
+	process := processes[0]
+	defer process.Close()
+
+	logging.Global.Info().
+		Int("pid", process.Pid()).
+		Msg("Found process")
+
+	f, err := os.Create(*out)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Creating snapshot failed")
+	}
+
+	if err := memory.WriteSnapshot(f, process); err != nil {
+		_ = f.Close()
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Capturing snapshot failed")
+	}
+
+	if err := f.Close(); err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Writing snapshot failed")
+	}
+
+	logging.Global.Info().
+		Str("file", *out).
+		Msg("Captured snapshot")
+}
@@ -0,0 +1,144 @@
+// Command valuesearch finds the address of a value shown in the game, like
+// the score, by scanning for it and then narrowing the candidates down in
+// later runs while the value changes. The candidates are kept in a file
+// between runs, so every run can read the game or a different snapshot.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"buttplugosu/internal/target"
+	"buttplugosu/pkg/logging"
+	"buttplugosu/pkg/memory"
+)
+
+func main() {
+	targetFlags := target.AddFlags()
+	file := flag.String("file", "values.search", "file the candidates are kept in between runs")
+	typ := flag.String("type", "int32", "type of the value: int16, int32, float32, float64 or string")
+	value := flag.String("value", "", "value to search for, or compare to with -compare equal")
+	compare := flag.String("compare", "", "narrow the candidates in -file down: changed, unchanged, increased, decreased or equal, a new search if empty")
+	show := flag.Int("show", 20, "most candidates printed")
+	flag.Parse()
+
+	process, err := targetFlags.Open()
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Opening target failed")
+	}
+	defer process.Close()
+
+	ctx := context.Background()
+
+	var search *memory.ValueSearch
+	if *compare == "" {
+		search = firstScan(ctx, process, *typ, *value)
+	} else {
+		search = nextScan(ctx, process, *file, *compare, *value)
+	}
+
+	f, err := os.Create(*file)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Creating search file failed")
+	}
+
+	if err := memory.WriteValueSearch(f, search); err != nil {
+		_ = f.Close()
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Saving candidates failed")
+	}
+
+	if err := f.Close(); err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Writing search file failed")
+	}
+
+	logging.Global.Info().
+		Str("type", search.Type.String()).
+		Int("candidates", len(search.Candidates)).
+		Str("file", *file).
+		Msg("Saved candidates")
+
+	for i, c := range search.Candidates {
+		if i == *show {
+			fmt.Printf("... %d more\n", len(search.Candidates)-i)
+			break
+		}
+
+		fmt.Printf("0x%X = %s\n", c.Addr, search.Format(c))
+	}
+}
+
+// firstScan starts a new search for value.
+func firstScan(ctx context.Context, p memory.Process, typ, value string) *memory.ValueSearch {
+	t, err := memory.ParseValueType(typ)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Invalid -type")
+	}
+
+	search, err := memory.FirstValueScan(ctx, p, t, value, &memory.ScanOptions{
+		Progress: func(p memory.ScanProgress) {
+			logging.Global.Debug().
+				Int64("MiB", p.BytesScanned>>20).
+				Int64("totalMiB", p.BytesTotal>>20).
+				Msg("Scanning")
+		},
+	})
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Scanning failed")
+	}
+
+	return search
+}
+
+// nextScan narrows the search saved in file down.
+func nextScan(ctx context.Context, p memory.Process, file, compare, value string) *memory.ValueSearch {
+	cmp, err := memory.ParseComparison(compare)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Invalid -compare")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Opening search file failed")
+	}
+
+	search, err := memory.ReadValueSearch(f)
+	_ = f.Close()
+	if err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Str("file", file).
+			Msg("Reading search file failed")
+	}
+
+	before := len(search.Candidates)
+	if err := search.NextScan(ctx, p, cmp, value); err != nil {
+		logging.Global.Fatal().
+			Err(err).
+			Msg("Scanning failed")
+	}
+
+	logging.Global.Info().
+		Int("before", before).
+		Int("after", len(search.Candidates)).
+		Msg("Narrowed candidates down")
+
+	return search
+}
@@ -3,12 +3,40 @@ package gameplay
 import (
 	"buttplugosu/pkg/logging"
 	"buttplugosu/pkg/memory"
-	"strings"
+	"errors"
 	"time"
 )
 
-func Init() {
-	if err := initBase(); err != nil {
+// Options configures where Init reads the game from.
+type Options struct {
+	// Record writes every memory read into this trace file.
+	Record string
+	// Replay reads the game from a trace file written with Record instead of
+	// a running osu!.
+	Replay string
+	// Strict fails on signatures that match more than one address instead
+	// of using the first match.
+	Strict bool
+	// Validate checks every read against the mapped regions of osu!, so
+	// stale offsets fail with the pointer that broke. Replays already
+	// contain the checked reads.
+	Validate bool
+	// Definitions is a file of signatures and offsets replacing the tags in
+	// read.go, see memory.Definitions.
+	Definitions string
+}
+
+// Init opens the game as opt says, nil reads a running osu!, and reads it
+// until osu! exits or the replay ends.
+func Init(opt *Options) {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	if err := initBase(opt); err != nil {
+		// Fatal exits, keep what was recorded so far
+		_ = Close()
+
 		logging.Global.Fatal().
 			Err(err).
 			Msg("Error occurred while initializing")
@@ -18,11 +46,25 @@ func Init() {
 		start := time.Now()
 
 		if DynamicAddresses.IsReady {
-			if err := memory.Read(
-				process,
+			tick.Reset()
+
+			if err := preSongSelectPlan.Read(
+				tick,
 				&patterns.PreSongSelectAddresses,
 				&menuData.PreSongSelectData,
 			); err != nil {
+				if errors.Is(err, memory.ErrTraceEnd) {
+					logging.Global.Info().
+						Msg("Replay finished")
+					return
+				}
+
+				if errors.Is(err, memory.ErrProcessExited) {
+					logging.Global.Info().
+						Msg("osu! exited")
+					return
+				}
+
 				logging.Global.
 					Err(err).
 					Msg("Failed to read 'PreSongSelectData'")
@@ -39,22 +81,38 @@ func Init() {
 	}
 }
 
+// Close releases the process, flushing and closing the trace when
+// recording. It can be called more than once, only the first call does
+// anything.
+func Close() error {
+	closeOnce.Do(func() {
+		if process != nil {
+			closeErr = process.Close()
+		}
+
+		if traceFile != nil {
+			closeErr = errors.Join(closeErr, traceFile.Close())
+		}
+	})
+
+	return closeErr
+}
+
 func handleRead() {
-	err := memory.Read(process, &patterns, &gameplayData)
-	if err != nil &&
-		!strings.Contains(err.Error(), "LeaderBoard") &&
-		!strings.Contains(err.Error(), "KeyOverlay") {
+	err := gameplayPlan.Read(tick, &patterns, &gameplayData)
+	if err != nil && !memory.OnlyOptional(err) {
 		return
 	}
 
 	// shit way tbh but it works
-	if previousHits != int(gameplayData.HitMiss) {
+	if previousHits != int(gameplayData.Score.HitMiss) {
 		vibratorQueue <- 300 * time.Millisecond
 
 		logging.Global.Debug().
-			Int16("count", gameplayData.HitMiss).
+			Int16("count", gameplayData.Score.HitMiss).
+			Stringer("mods", gameplayData.Score.Mods).
 			Msg("Queueing vibration due to miss")
 
-		previousHits = int(gameplayData.HitMiss)
+		previousHits = int(gameplayData.Score.HitMiss)
 	}
 }
@@ -3,31 +3,197 @@ package gameplay
 import (
 	"buttplugosu/pkg/logging"
 	"buttplugosu/pkg/memory"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"time"
 )
 
 var osuProcessRegex = regexp.MustCompile(`.*osu!\.exe.*`)
+
+// validateInterval is how often the regions checked by Options.Validate
+// are refreshed.
+const validateInterval = 5 * time.Second
+
 var patterns staticAddresses
 var menuData menuD
 var gameplayData gameplayD
 
-func initBase() error {
-	var err error
+// plans read the structs above every tick, compiled once by initBase
+var preSongSelectPlan, gameplayPlan *memory.Plan
 
-	// find osu process
-	processes, err = memory.FindProcess(osuProcessRegex, "osu!lazer", "osu!framework")
+// openProcess finds the osu! process or opens the trace to replay.
+func openProcess(opt *Options) (memory.Process, error) {
+	if opt.Replay != "" {
+		f, err := os.Open(opt.Replay)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.Global.Info().
+			Str("file", opt.Replay).
+			Msg("Replaying trace")
+
+		rep, err := memory.NewReplayer(f, true)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		traceFile = f
+		return rep, nil
+	}
+
+	processes, err := memory.FindProcess(osuProcessRegex, "osu!lazer", "osu!framework")
+	if err != nil {
+		return nil, err
+	}
+
+	var p memory.Process = processes[0]
+	if opt.Validate {
+		// below the recorder, so replays fail with the same errors
+		p = memory.NewMapChecker(p, validateInterval)
+	}
+
+	if opt.Record == "" {
+		return p, nil
+	}
+
+	f, err := os.Create(opt.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Global.Info().
+		Str("file", opt.Record).
+		Msg("Recording trace")
+
+	rec, err := memory.NewRecorder(p, f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	traceFile = f
+	return rec, nil
+}
+
+// reportSignature logs where a signature matched, fallback alternatives as
+// info and ambiguous signatures as a warning.
+func reportSignature(r memory.SignatureReport) {
+	var regions []string
+	for _, reg := range r.Regions() {
+		regions = append(regions, fmt.Sprintf("0x%x %s %s %s",
+			reg.Start(), reg.Protection(), reg.Type(), reg.Module()))
+	}
+
+	event := logging.Global.Debug()
+	if r.Alternative > 0 {
+		event = logging.Global.Info()
+	}
+	if r.Ambiguous() || r.Err != nil {
+		event = logging.Global.Warn()
+	}
+
+	event.
+		Str("field", r.Field).
+		Str("signature", r.Signature).
+		Int("alternative", r.Alternative).
+		Str("pattern", r.Pattern).
+		Int("matches", len(r.Matches)).
+		Strs("regions", regions).
+		Msg("Resolved signature")
+}
+
+// loadDefinitions reads the definitions file of opt, nil if there is none.
+func loadDefinitions(opt *Options) (*memory.Definitions, error) {
+	if opt.Definitions == "" {
+		return nil, nil
+	}
+
+	defs, err := memory.LoadDefinitions(opt.Definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Global.Info().
+		Str("file", opt.Definitions).
+		Str("game", defs.Game).
+		Int("signatures", len(defs.Signatures)).
+		Int("variables", len(defs.Variables)).
+		Msg("Loaded definitions")
+
+	return defs, nil
+}
+
+func initBase(opt *Options) error {
+	defs, err := loadDefinitions(opt)
 	if err != nil {
 		return err
 	}
 
-	process = processes[0]
+	// check the memory tags before touching the game
+	if preSongSelectPlan, err = memory.CompileDefinitions(&patterns.PreSongSelectAddresses, &menuData.PreSongSelectData, defs); err != nil {
+		return err
+	}
+
+	if gameplayPlan, err = memory.CompileDefinitions(&patterns, &gameplayData, defs); err != nil {
+		return err
+	}
+
+	if err = defs.CheckFields(preSongSelectPlan, gameplayPlan); err != nil {
+		return err
+	}
+
+	if err = defs.CheckSignatures(&patterns); err != nil {
+		return err
+	}
+
+	// find osu process
+	process, err = openProcess(opt)
+	if err != nil {
+		return err
+	}
 
 	logging.Global.Info().
 		Int("pid", process.Pid()).
 		Msg("Found process")
 
+	tick = memory.NewPageCache(process)
+
+	scanOpts := &memory.ScanOptions{
+		Report: reportSignature,
+		Progress: func(p memory.ScanProgress) {
+			logging.Global.Debug().
+				Int64("MiB", p.BytesScanned>>20).
+				Int("regions", p.RegionsDone).
+				Int("found", p.Found).
+				Int("total", p.Total).
+				Msg("Scanning")
+		},
+	}
+
+	if defs != nil {
+		scanOpts.Signatures = defs.Signatures
+	}
+
+	if opt.Strict {
+		scanOpts.Strict = memory.StrictFail
+	}
+
+	if opt.Record != "" || opt.Replay != "" {
+		// traces only replay if the reads happen in the recorded order
+		scanOpts.Workers = 1
+	} else if dir, err := os.UserCacheDir(); err == nil {
+		scanOpts.Cache = filepath.Join(dir, "buttosu", "signatures.json")
+	}
+
+	ctx := context.Background()
+
 	// resolve song select pattern
-	err = memory.ResolvePatterns(process, &patterns.PreSongSelectAddresses)
+	err = memory.ResolvePatternsContext(ctx, process, &patterns.PreSongSelectAddresses, scanOpts)
 	if err != nil {
 		logging.Global.
 			Err(err).
@@ -36,7 +202,7 @@ func initBase() error {
 	}
 
 	// read pre song select data
-	if err = memory.Read(process, &patterns.PreSongSelectAddresses, &menuData.PreSongSelectData); err != nil {
+	if err = preSongSelectPlan.Read(process, &patterns.PreSongSelectAddresses, &menuData.PreSongSelectData); err != nil {
 		logging.Global.
 			Err(err).
 			Msg("Reading failed")
@@ -47,7 +213,7 @@ func initBase() error {
 	logging.Global.Info().
 		Msg("Resolving patterns")
 
-	err = memory.ResolvePatterns(process, &patterns)
+	err = memory.ResolvePatternsContext(ctx, process, &patterns, scanOpts)
 	if err != nil {
 		return err
 	}
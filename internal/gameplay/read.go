@@ -6,7 +6,7 @@ type PreSongSelectAddresses struct {
 }
 
 type PreSongSelectData struct {
-	Status uint32 `memory:"[Status - 0x4]"`
+	Status Status `memory:"[Status - 0x4]"`
 }
 
 type staticAddresses struct {
@@ -40,6 +40,8 @@ func (staticAddresses) Leaderboard() string {
 	return "[[[LeaderboardBase+0x1] + 0x4] + 0x7C] + 0x24"
 }
 
+// menuD is not read yet apart from its PreSongSelectData, definitions can
+// only define the fields of PreSongSelectData and gameplayD.
 type menuD struct {
 	PreSongSelectData
 	MenuGameMode       int32   `memory:"[Base - 0x33]"`
@@ -68,25 +70,29 @@ type menuD struct {
 }
 
 type gameplayD struct {
-	Retries             int32   `memory:"[Base - 0x33] + 0x8"`
-	PlayerName          string  `memory:"[[[Ruleset + 0x68] + 0x38] + 0x28]"`
-	ModsXor1            int32   `memory:"[[[Ruleset + 0x68] + 0x38] + 0x1C] + 0xC"`
-	ModsXor2            int32   `memory:"[[[Ruleset + 0x68] + 0x38] + 0x1C] + 0x8"`
-	HitErrors           []int32 `memory:"[[[Ruleset + 0x68] + 0x38] + 0x38]"`
-	Mode                int32   `memory:"[[Ruleset + 0x68] + 0x38] + 0x64"`
-	MaxCombo            int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x68"`
+	Retries             int32   `memory:"[Base - 0x33] + 0x8" validate:"nonneg"`
+	Score               scoreD  `memory:"[[Ruleset + 0x68] + 0x38]"`
 	ScoreV2             int32   `memory:"Ruleset + 0x100"`
-	Hit100              int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x88"`
-	Hit300              int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x8A"`
-	Hit50               int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x8C"`
-	HitGeki             int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x8E"`
-	HitKatu             int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x90"`
-	HitMiss             int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x92"`
-	Combo               int16   `memory:"[[Ruleset + 0x68] + 0x38] + 0x94"`
-	PlayerHPSmooth      float64 `memory:"[[Ruleset + 0x68] + 0x40] + 0x14"`
-	PlayerHP            float64 `memory:"[[Ruleset + 0x68] + 0x40] + 0x1C"`
-	Accuracy            float64 `memory:"[[Ruleset + 0x68] + 0x48] + 0xC"`
-	LeaderBoard         uint32  `memory:"[Ruleset + 0x7C] + 0x24"`
-	KeyOverlayArrayAddr uint32  `memory:"[[Ruleset + 0xB0] + 0x10] + 0x4"` //has to be at the end due to memory not liking dead pointers, TODO: Fix this memory-side
-	// Score               int32   `memory:"[[Ruleset + 0x68] + 0x38] + 0x78"`
+	PlayerHPSmooth      float64 `memory:"[[Ruleset + 0x68] + 0x40] + 0x14" validate:"range=0,200"`
+	PlayerHP            float64 `memory:"[[Ruleset + 0x68] + 0x40] + 0x1C" validate:"range=0,200"`
+	Accuracy            float64 `memory:"[[Ruleset + 0x68] + 0x48] + 0xC" validate:"range=0,100"`
+	LeaderBoard         uint32  `memory:"[Ruleset + 0x7C] + 0x24,optional"`
+	KeyOverlayArrayAddr uint32  `memory:"[[Ruleset + 0xB0] + 0x10] + 0x4,optional"`
+}
+
+// scoreD is the score of the current play
+type scoreD struct {
+	PlayerName string  `memory:"[Self + 0x28]"`
+	Mods       Mods    `memory:"[Self + 0x1C]"`
+	HitErrors  []int32 `memory:"[Self + 0x38]"`
+	Mode       int32   `memory:"Self + 0x64" validate:"range=0,3"`
+	MaxCombo   int16   `memory:"Self + 0x68" validate:"nonneg"`
+	Hit100     int16   `memory:"Self + 0x88" validate:"nonneg"`
+	Hit300     int16   `memory:"Self + 0x8A" validate:"nonneg"`
+	Hit50      int16   `memory:"Self + 0x8C" validate:"nonneg"`
+	HitGeki    int16   `memory:"Self + 0x8E" validate:"nonneg"`
+	HitKatu    int16   `memory:"Self + 0x90" validate:"nonneg"`
+	HitMiss    int16   `memory:"Self + 0x92" validate:"nonneg"`
+	Combo      int16   `memory:"Self + 0x94" validate:"nonneg"`
+	// Score   int32   `memory:"Self + 0x78"`
 }
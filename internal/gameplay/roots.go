@@ -0,0 +1,54 @@
+package gameplay
+
+import (
+	"buttplugosu/pkg/memory"
+	"context"
+	"errors"
+)
+
+// ResolveRoots resolves the signatures of staticAddresses in p and returns
+// the address of every variable the memory tags can refer to, the roots of
+// tools looking for the offsets of new fields. defs replace signatures and
+// variables like they do for the game reader, nil keeps the compiled-in
+// ones. Roots that failed are left out and reported in the error.
+func ResolveRoots(ctx context.Context, p memory.Process, defs *memory.Definitions, opts ...*memory.ScanOptions) (map[string]int64, error) {
+	var addrs staticAddresses
+
+	if err := defs.CheckSignatures(&addrs); err != nil {
+		return nil, err
+	}
+
+	opt := &memory.ScanOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+
+	if defs != nil {
+		opt.Signatures = defs.Signatures
+	}
+
+	errPre := memory.ResolvePatternsContext(ctx, p, &addrs.PreSongSelectAddresses, opt)
+	errStatic := memory.ResolvePatternsContext(ctx, p, &addrs, opt)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	errs := []error{errPre, errStatic}
+	roots := map[string]int64{}
+
+	for _, name := range memory.Variables(&addrs, defs) {
+		addr, err := memory.EvaluateDefinitions(p, &addrs, name, defs)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// signatures that were not found stay 0
+		if addr != 0 {
+			roots[name] = addr
+		}
+	}
+
+	return roots, errors.Join(errs...)
+}
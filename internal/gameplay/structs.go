@@ -1,15 +1,26 @@
 package gameplay
 
-import "buttplugosu/pkg/memory"
+import (
+	"buttplugosu/pkg/memory"
+	"os"
+	"sync"
+)
 
 type dynamicAddresses struct {
 	IsReady bool
 }
 
 var (
-	processes []memory.Process
-	process   memory.Process
-	procerr   error
+	process memory.Process
+	procerr error
+
+	// tick caches the pages of process read during one tick of Init
+	tick *memory.PageCache
+
+	// traceFile is the file recorded into or replayed, closed with process
+	traceFile *os.File
+	closeOnce sync.Once
+	closeErr  error
 
 	previousHits     = 0
 	DynamicAddresses = dynamicAddresses{}
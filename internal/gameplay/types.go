@@ -0,0 +1,146 @@
+package gameplay
+
+import (
+	"buttplugosu/pkg/memory"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Status is the screen osu! is on.
+type Status uint32
+
+const (
+	StatusMainMenu                 Status = 0
+	StatusEditingMap               Status = 1
+	StatusPlaying                  Status = 2
+	StatusGameShutdownAnimation    Status = 3
+	StatusSongSelectEdit           Status = 4
+	StatusSongSelect               Status = 5
+	StatusResultsScreen            Status = 7
+	StatusGameStartupAnimation     Status = 10
+	StatusMultiplayerRooms         Status = 11
+	StatusMultiplayerRoom          Status = 12
+	StatusMultiplayerSongSelect    Status = 13
+	StatusMultiplayerResultsScreen Status = 14
+	StatusOsuDirect                Status = 15
+	StatusProcessingBeatmaps       Status = 19
+	StatusTourney                  Status = 22
+)
+
+var statusNames = map[Status]string{
+	StatusMainMenu:                 "MainMenu",
+	StatusEditingMap:               "EditingMap",
+	StatusPlaying:                  "Playing",
+	StatusGameShutdownAnimation:    "GameShutdownAnimation",
+	StatusSongSelectEdit:           "SongSelectEdit",
+	StatusSongSelect:               "SongSelect",
+	StatusResultsScreen:            "ResultsScreen",
+	StatusGameStartupAnimation:     "GameStartupAnimation",
+	StatusMultiplayerRooms:         "MultiplayerRooms",
+	StatusMultiplayerRoom:          "MultiplayerRoom",
+	StatusMultiplayerSongSelect:    "MultiplayerSongSelect",
+	StatusMultiplayerResultsScreen: "MultiplayerResultsScreen",
+	StatusOsuDirect:                "OsuDirect",
+	StatusProcessingBeatmaps:       "ProcessingBeatmaps",
+	StatusTourney:                  "Tourney",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+
+	return "Status(" + strconv.Itoa(int(s)) + ")"
+}
+
+func (s *Status) UnmarshalMemory(r io.ReaderAt, addr int64) error {
+	v, err := memory.ReadUint32(r, addr)
+	*s = Status(v)
+	return err
+}
+
+// Mods are the mods of a play. osu! stores them as two ints that have to be
+// XORed, the address of a Mods field is the object holding them.
+type Mods uint32
+
+const (
+	ModNoFail Mods = 1 << iota
+	ModEasy
+	ModTouchDevice
+	ModHidden
+	ModHardRock
+	ModSuddenDeath
+	ModDoubleTime
+	ModRelax
+	ModHalfTime
+	ModNightcore
+	ModFlashlight
+	ModAutoplay
+	ModSpunOut
+	ModAutopilot
+	ModPerfect
+)
+
+var modNames = []struct {
+	mod  Mods
+	name string
+}{
+	{ModNoFail, "NF"},
+	{ModEasy, "EZ"},
+	{ModTouchDevice, "TD"},
+	{ModHidden, "HD"},
+	{ModHardRock, "HR"},
+	{ModSuddenDeath, "SD"},
+	{ModDoubleTime, "DT"},
+	{ModRelax, "RX"},
+	{ModHalfTime, "HT"},
+	{ModNightcore, "NC"},
+	{ModFlashlight, "FL"},
+	{ModAutoplay, "AT"},
+	{ModSpunOut, "SO"},
+	{ModAutopilot, "AP"},
+	{ModPerfect, "PF"},
+}
+
+// Has reports whether all of mods are enabled.
+func (m Mods) Has(mods Mods) bool {
+	return m&mods == mods
+}
+
+func (m Mods) String() string {
+	if m == 0 {
+		return "NM"
+	}
+
+	var b strings.Builder
+
+	for _, mod := range modNames {
+		// nightcore and perfect always come with double time and sudden death
+		if (mod.mod == ModDoubleTime && m.Has(ModNightcore)) ||
+			(mod.mod == ModSuddenDeath && m.Has(ModPerfect)) {
+			continue
+		}
+
+		if m.Has(mod.mod) {
+			b.WriteString(mod.name)
+		}
+	}
+
+	return b.String()
+}
+
+func (m *Mods) UnmarshalMemory(r io.ReaderAt, addr int64) error {
+	xor1, err := memory.ReadInt32(r, addr, 0xC)
+	if err != nil {
+		return err
+	}
+
+	xor2, err := memory.ReadInt32(r, addr, 0x8)
+	if err != nil {
+		return err
+	}
+
+	*m = Mods(xor1 ^ xor2)
+	return nil
+}
@@ -0,0 +1,69 @@
+package gameplay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{StatusPlaying, "Playing"},
+		{StatusMultiplayerResultsScreen, "MultiplayerResultsScreen"},
+		{Status(6), "Status(6)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("Status(%d).String() = %q, want %q", uint32(tt.status), got, tt.want)
+		}
+	}
+
+	r := bytes.NewReader([]byte{0, 0, 0, 0, 5, 0, 0, 0})
+
+	var s Status
+	if err := s.UnmarshalMemory(r, 4); err != nil || s != StatusSongSelect {
+		t.Errorf("UnmarshalMemory = %v, %v, want SongSelect", s, err)
+	}
+
+	if err := s.UnmarshalMemory(r, 8); err == nil {
+		t.Error("UnmarshalMemory past the end succeeded")
+	}
+}
+
+func TestMods(t *testing.T) {
+	tests := []struct {
+		mods Mods
+		want string
+	}{
+		{0, "NM"},
+		{ModHidden | ModHardRock, "HDHR"},
+		{ModDoubleTime | ModNightcore | ModHidden, "HDNC"},
+		{ModSuddenDeath | ModPerfect, "PF"},
+		{ModSuddenDeath | ModDoubleTime, "SDDT"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mods.String(); got != tt.want {
+			t.Errorf("Mods(%d).String() = %q, want %q", uint32(tt.mods), got, tt.want)
+		}
+	}
+
+	// the object holding the mods has them XORed with a key at +0x8 and
+	// +0xC
+	b := make([]byte, 0x10)
+	binary.LittleEndian.PutUint32(b[0x8:], 0x1234)
+	binary.LittleEndian.PutUint32(b[0xC:], 0x1234^uint32(ModHidden|ModDoubleTime))
+
+	var m Mods
+	if err := m.UnmarshalMemory(bytes.NewReader(b), 0); err != nil || m != ModHidden|ModDoubleTime {
+		t.Errorf("UnmarshalMemory = %v, %v, want HDDT", m, err)
+	}
+
+	if err := m.UnmarshalMemory(bytes.NewReader(b[:0xC]), 0); err == nil {
+		t.Error("UnmarshalMemory of a cut off object succeeded")
+	}
+}
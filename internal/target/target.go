@@ -0,0 +1,65 @@
+// Package target opens the process the tools in cmd look at: a running
+// osu!, a snapshot written by cmd/snapshot or a Windows minidump.
+package target
+
+import (
+	"flag"
+	"regexp"
+
+	"buttplugosu/pkg/logging"
+	"buttplugosu/pkg/memory"
+)
+
+// Flags are the command line flags choosing the target.
+type Flags struct {
+	Process  string
+	Snapshot string
+	Dump     string
+}
+
+// AddFlags registers -process, -snapshot and -dump on the default flag set.
+func AddFlags() *Flags {
+	f := &Flags{}
+	flag.StringVar(&f.Process, "process", `.*osu!\.exe.*`, "regular expression matching the process")
+	flag.StringVar(&f.Snapshot, "snapshot", "", "read a snapshot instead of a running process")
+	flag.StringVar(&f.Dump, "dump", "", "read a minidump instead of a running process")
+	return f
+}
+
+// Open opens the target, a snapshot or minidump if one was given. The
+// layout of the process is detected from its executable when possible.
+func (f *Flags) Open() (memory.Process, error) {
+	var p memory.Process
+	var err error
+
+	switch {
+	case f.Snapshot != "":
+		p, err = memory.OpenSnapshot(f.Snapshot)
+	case f.Dump != "":
+		p, err = memory.OpenMinidump(f.Dump)
+	default:
+		var processes []memory.Process
+		processes, err = memory.FindProcess(regexp.MustCompile(f.Process), "osu!lazer", "osu!framework")
+		if err == nil {
+			p = processes[0]
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Global.Info().
+		Int("pid", p.Pid()).
+		Msg("Opened target")
+
+	if layout, err := memory.DetectLayout(p); err == nil {
+		p = memory.WithLayout(p, layout)
+	} else {
+		logging.Global.Warn().
+			Err(err).
+			Msg("Detecting layout failed, assuming 32-bit")
+	}
+
+	return p, nil
+}
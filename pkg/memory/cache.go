@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// maxCacheEntries is how many processes a signature cache remembers.
+const maxCacheEntries = 8
+
+// processIdentity tells processes apart across restarts of our tool.
+type processIdentity struct {
+	Executable string `json:"executable"`
+	Hash       string `json:"hash,omitempty"`
+	Pid        int    `json:"pid"`
+	Started    int64  `json:"started,omitempty"`
+}
+
+type cacheEntry struct {
+	Process    processIdentity  `json:"process"`
+	Signatures map[string]int64 `json:"signatures"`
+}
+
+// signatureCache is the file behind ScanOptions.Cache, the most recently
+// used process comes first.
+type signatureCache struct {
+	Entries []cacheEntry `json:"entries"`
+}
+
+func identify(p Process) processIdentity {
+	id := processIdentity{Pid: p.Pid()}
+
+	if exe, err := p.ExecutablePath(); err == nil {
+		id.Executable = exe
+		id.Hash, _ = hashFile(exe)
+	}
+
+	if s, ok := p.(started); ok {
+		if t, err := s.StartTime(); err == nil {
+			id.Started = t.UnixNano()
+		}
+	}
+
+	return id
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadCache reads a signature cache. A missing or broken file is an empty
+// cache, it only ever saves time.
+func loadCache(path string) *signatureCache {
+	var c signatureCache
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return &c
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return &signatureCache{}
+	}
+
+	return &c
+}
+
+func (c *signatureCache) save(path string) error {
+	b, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
+
+// lookup returns the signatures resolved for id, nil if id is unknown.
+func (c *signatureCache) lookup(id processIdentity) map[string]int64 {
+	for _, e := range c.Entries {
+		if e.Process == id {
+			return e.Signatures
+		}
+	}
+
+	return nil
+}
+
+// store merges sigs into the entry of id and moves it to the front.
+func (c *signatureCache) store(id processIdentity, sigs map[string]int64) {
+	entry := cacheEntry{Process: id, Signatures: map[string]int64{}}
+
+	for i, e := range c.Entries {
+		if e.Process == id {
+			entry = e
+			c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+			break
+		}
+	}
+
+	for sig, addr := range sigs {
+		entry.Signatures[sig] = addr
+	}
+
+	c.Entries = append([]cacheEntry{entry}, c.Entries...)
+	if len(c.Entries) > maxCacheEntries {
+		c.Entries = c.Entries[:maxCacheEntries]
+	}
+}
+
+// verify reports whether pat still matches at addr.
+func verify(r io.ReaderAt, pat pattern, addr int64) bool {
+	buf := make([]byte, len(pat.bytes))
+	if _, err := readFullAt(r, buf, addr); err != nil {
+		return false
+	}
+
+	return pat.match(buf)
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePatternsCache(t *testing.T) {
+	type cacheAddresses struct {
+		Unique int64 `sig:"11 22 33 ( ?? ?? ?? ?? )"`
+	}
+
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache", "signatures.json")
+
+	p := newStrictProcess()
+	p.exe = filepath.Join(dir, "osu!.exe")
+	if err := os.WriteFile(p.exe, []byte("MZ"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// resolve reports whether it had to scan
+	resolve := func(p *fakeProcess) (int64, bool) {
+		t.Helper()
+
+		scanned := false
+		opt := &ScanOptions{Cache: cachePath, Workers: 1, Progress: func(ScanProgress) { scanned = true }}
+
+		var addrs cacheAddresses
+		if err := ResolvePatternsContext(context.Background(), p, &addrs, opt); err != nil {
+			t.Fatal(err)
+		}
+
+		return addrs.Unique, scanned
+	}
+
+	if addr, scanned := resolve(p); addr != 0x5000 || !scanned {
+		t.Fatalf("first resolve = 0x%x, scanned %v", addr, scanned)
+	}
+
+	entry := loadCache(cachePath).lookup(identify(p))
+	if entry["11 22 33 ( ?? ?? ?? ?? )"] != 0x10010 {
+		t.Fatalf("cached %v, want the match at 0x10010", entry)
+	}
+
+	// the operand changed, the match is still there
+	p.write(0x10013, []byte{0x00, 0x51})
+	if addr, scanned := resolve(p); addr != 0x5100 || scanned {
+		t.Errorf("cached resolve = 0x%x, scanned %v, want 0x5100 without a scan", addr, scanned)
+	}
+
+	// the code moved
+	p.write(0x10010, make([]byte, 7))
+	p.write(0x20040, []byte{0x11, 0x22, 0x33, 0x00, 0x52, 0x00, 0x00})
+	if addr, scanned := resolve(p); addr != 0x5200 || !scanned {
+		t.Errorf("resolve after the move = 0x%x, scanned %v, want 0x5200 from a scan", addr, scanned)
+	}
+
+	if entry := loadCache(cachePath).lookup(identify(p)); entry["11 22 33 ( ?? ?? ?? ?? )"] != 0x20040 {
+		t.Errorf("cached %v after the move, want the match at 0x20040", entry)
+	}
+
+	// another process of the same executable
+	p.pid++
+	if _, scanned := resolve(p); !scanned {
+		t.Error("resolve in another process used the cache")
+	}
+
+	// an update of osu! in the same process id
+	if err := os.WriteFile(p.exe, []byte("MZ updated"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, scanned := resolve(p); !scanned {
+		t.Error("resolve after an update used the cache")
+	}
+
+	// strict mode checks uniqueness and never trusts the cache
+	scanned := false
+	opt := &ScanOptions{Cache: cachePath, Strict: StrictWarn, Workers: 1, Progress: func(ScanProgress) { scanned = true }}
+	if err := ResolvePatternsContext(context.Background(), p, &cacheAddresses{}, opt); err != nil || !scanned {
+		t.Errorf("strict resolve = %v, scanned %v", err, scanned)
+	}
+}
+
+func TestSignatureCacheStore(t *testing.T) {
+	c := loadCache(filepath.Join(t.TempDir(), "missing.json"))
+
+	for pid := 0; pid < maxCacheEntries+2; pid++ {
+		c.store(processIdentity{Pid: pid}, map[string]int64{"AB": int64(pid)})
+	}
+
+	if len(c.Entries) != maxCacheEntries || c.Entries[0].Process.Pid != maxCacheEntries+1 {
+		t.Fatalf("%d entries, the first of %d, want %d, the first of the last process",
+			len(c.Entries), c.Entries[0].Process.Pid, maxCacheEntries)
+	}
+
+	if c.lookup(processIdentity{Pid: 0}) != nil {
+		t.Error("the oldest process was not dropped")
+	}
+
+	// storing merges and moves the entry to the front
+	c.store(processIdentity{Pid: 5}, map[string]int64{"CD": 1})
+	if e := c.Entries[0]; e.Process.Pid != 5 || e.Signatures["AB"] != 5 || e.Signatures["CD"] != 1 {
+		t.Errorf("entry after merging %+v", e)
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := loadCache(path); len(c.Entries) != 0 {
+		t.Errorf("broken cache has %d entries", len(c.Entries))
+	}
+}
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// missRefresh is how old the regions of a MapChecker have to be before a
+// read outside of them refreshes them, so memory the game just allocated
+// isn't reported as unmapped. Every page only refreshes the regions once
+// per interval, dead optional pointers would otherwise list the regions
+// many times a second.
+const missRefresh = 100 * time.Millisecond
+
+// MapChecker is a Process that checks every read against the readable
+// regions of another process before reading. A stale offset then fails with
+// an UnmappedError naming the address instead of reading garbage, or
+// failing with whatever the system says.
+//
+// The regions are refreshed when they are older than the interval given to
+// NewMapChecker, and once per interval for every page a read is refused in.
+// Everything but ReadAt is forwarded, closing the checker closes the
+// process.
+type MapChecker struct {
+	Process
+	interval time.Duration
+
+	mu        sync.Mutex
+	regions   []span
+	refreshed time.Time
+
+	// missed are the pages of refused reads since the last refresh that
+	// was due to the interval
+	missed map[int64]bool
+}
+
+// span is a run of readable memory from start up to end.
+type span struct {
+	start, end int64
+}
+
+func NewMapChecker(p Process, interval time.Duration) *MapChecker {
+	return &MapChecker{Process: p, interval: interval, missed: map[int64]bool{}}
+}
+
+// UnmappedError is a read a MapChecker refused, Addr is the first byte of
+// it that is not in a readable region.
+type UnmappedError struct {
+	Addr int64
+}
+
+func (e *UnmappedError) Error() string {
+	return fmt.Sprintf("0x%x is not in a readable region", e.Addr)
+}
+
+func (e *UnmappedError) Unwrap() error {
+	return ErrUnmapped
+}
+
+// PointerError is a pointer chain that led to unmapped memory, found by a
+// MapChecker, the system or a snapshot. Hop counts the pointers followed
+// like FieldError.Hop.
+type PointerError struct {
+	Pointer int64
+	Hop     int
+	Err     error
+}
+
+func (e *PointerError) Error() string {
+	return fmt.Sprintf("pointer 0x%x at hop %d is not mapped", e.Pointer, e.Hop)
+}
+
+func (e *PointerError) Unwrap() error {
+	return e.Err
+}
+
+// pointerError turns an unmapped read at addr after hop pointers into a
+// PointerError, other errors and reads of the base address are returned as
+// they are. Any ErrUnmapped counts, not just an UnmappedError, so replayed
+// traces fail like the run they recorded.
+func pointerError(err error, addr int64, hop int) error {
+	if hop == 0 || !errors.Is(err, ErrUnmapped) {
+		return err
+	}
+
+	return &PointerError{Pointer: addr, Hop: hop, Err: err}
+}
+
+func (c *MapChecker) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	if addr, ok := c.check(off, off+int64(len(b))); !ok {
+		return 0, &UnmappedError{Addr: addr}
+	}
+
+	return c.Process.ReadAt(b, off)
+}
+
+// check reports whether start up to end is readable, and otherwise the
+// first address that is not.
+func (c *MapChecker) check(start, end int64) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.refreshed) >= c.interval {
+		c.refresh()
+		c.missed = map[int64]bool{}
+	}
+
+	addr, ok := c.covered(start, end)
+	if ok {
+		return addr, ok
+	}
+
+	page := addr &^ (pageSize - 1)
+	if !c.missed[page] && time.Since(c.refreshed) >= missRefresh {
+		c.refresh()
+		addr, ok = c.covered(start, end)
+	}
+
+	if !ok {
+		c.missed[addr&^(pageSize-1)] = true
+		c.missed[page] = true
+	}
+
+	return addr, ok
+}
+
+// covered reports whether the regions cover start up to end, and otherwise
+// the first address they don't.
+func (c *MapChecker) covered(start, end int64) (int64, bool) {
+	i := sort.Search(len(c.regions), func(i int) bool {
+		return c.regions[i].end > start
+	})
+
+	if i == len(c.regions) || c.regions[i].start > start {
+		return start, false
+	}
+
+	if c.regions[i].end < end {
+		return c.regions[i].end, false
+	}
+
+	return 0, true
+}
+
+// refresh reads the readable regions of the process and merges adjacent
+// ones. A failure keeps the regions read before.
+func (c *MapChecker) refresh() {
+	c.refreshed = time.Now()
+
+	maps, err := c.Process.Maps()
+	if err != nil {
+		return
+	}
+
+	var regions []span
+	for _, m := range maps {
+		if !Readable(m) {
+			continue
+		}
+
+		regions = append(regions, span{m.Start(), m.Start() + m.Size()})
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].start < regions[j].start
+	})
+
+	merged := regions[:0]
+	for _, s := range regions {
+		if n := len(merged); n > 0 && merged[n-1].end >= s.start {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+
+		merged = append(merged, s)
+	}
+
+	c.regions = merged
+}
+
+func (c *MapChecker) Layout() Layout {
+	return layoutOf(c.Process)
+}
+
+func (c *MapChecker) Alive() bool {
+	return isAlive(c.Process)
+}
+
+func (c *MapChecker) StartTime() (time.Time, error) {
+	if s, ok := c.Process.(started); ok {
+		return s.StartTime()
+	}
+
+	return time.Time{}, errors.New("process start time unknown")
+}
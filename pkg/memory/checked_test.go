@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMapChecker(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+	p.mapRegion(0x2000, 0x1000)
+	p.write(0x1ffe, []byte{0x11, 0x11})
+	p.write(0x2000, []byte{0x22, 0x22})
+	p.mapRegion(0x4000, 0x1000).prot = 0
+
+	c := NewMapChecker(p, time.Hour)
+
+	// adjacent regions are merged
+	if v, err := ReadUint32(c, 0x1ffe); err != nil || v != 0x22221111 {
+		t.Errorf("ReadUint32 across regions = 0x%x, %v", v, err)
+	}
+
+	tests := []struct {
+		off  int64
+		addr int64
+	}{
+		{0x3000, 0x3000},
+		{0x2ffe, 0x3000},
+		// mapped, but not readable
+		{0x4000, 0x4000},
+	}
+
+	for _, tt := range tests {
+		reads := p.reads
+
+		var uerr *UnmappedError
+		_, err := c.ReadAt(make([]byte, 4), tt.off)
+		if !errors.As(err, &uerr) || uerr.Addr != tt.addr || !errors.Is(err, ErrUnmapped) {
+			t.Errorf("ReadAt(0x%x) = %v, want an UnmappedError at 0x%x", tt.off, err, tt.addr)
+		}
+
+		if p.reads != reads {
+			t.Errorf("ReadAt(0x%x) read the process", tt.off)
+		}
+	}
+
+	// new regions are found once the regions are old enough, but a page
+	// refused before only refreshes them once per interval
+	p.mapRegion(0x3000, 0x1000)
+	p.mapRegion(0x5000, 0x1000)
+	if _, err := c.ReadAt(make([]byte, 4), 0x3000); !errors.Is(err, ErrUnmapped) {
+		t.Errorf("ReadAt of a new region right away = %v, want ErrUnmapped", err)
+	}
+
+	time.Sleep(missRefresh)
+	if _, err := c.ReadAt(make([]byte, 4), 0x3000); !errors.Is(err, ErrUnmapped) {
+		t.Errorf("ReadAt of a refused page again = %v, want ErrUnmapped", err)
+	}
+
+	if _, err := c.ReadAt(make([]byte, 4), 0x5000); err != nil {
+		t.Errorf("ReadAt of a new region later: %v", err)
+	}
+
+	if _, err := c.ReadAt(make([]byte, 4), 0x3000); err != nil {
+		t.Errorf("ReadAt of a refused page after a refresh: %v", err)
+	}
+}
+
+func TestPointerErrorHops(t *testing.T) {
+	p, addrs := newPlanProcess()
+	c := NewMapChecker(p, time.Hour)
+
+	var data struct {
+		Base  int32 `memory:"Base + 0x2000"`
+		Value int32 `memory:"Root + 0x2000"`
+		Chain int32 `memory:"[Root + 0x10] + 0x4"`
+	}
+
+	var rerr ReadError
+	if err := Read(c, addrs, &data); !errors.As(err, &rerr) || len(rerr.Required) != 3 {
+		t.Fatalf("Read = %v, want every field to fail", err)
+	}
+
+	tests := []struct {
+		field   string
+		hop     int
+		pointer int64
+	}{
+		// the addresses themselves are no pointers
+		{"Base", 0, 0},
+		{"Value", 1, 0x3100},
+		{"Chain", 2, 0x9004},
+	}
+
+	for i, tt := range tests {
+		ferr := rerr.Required[i]
+
+		var uerr *UnmappedError
+		if ferr.Field != tt.field || ferr.Hop != tt.hop || !errors.As(ferr, &uerr) {
+			t.Errorf("%s: error %+v, want an UnmappedError at hop %d", tt.field, ferr, tt.hop)
+		}
+
+		var perr *PointerError
+		if ok := errors.As(ferr, &perr); ok != (tt.hop > 0) {
+			t.Errorf("%s: PointerError %v, want one %v", tt.field, ok, tt.hop > 0)
+		} else if ok && (perr.Hop != tt.hop || perr.Pointer != tt.pointer) {
+			t.Errorf("%s: %+v, want pointer 0x%x at hop %d", tt.field, perr, tt.pointer, tt.hop)
+		}
+	}
+
+	// the read functions number their hops the same way
+	var perr *PointerError
+	if _, err := ReadInt32(c, 0x1000, 0, 0x10, 0x4); !errors.As(err, &perr) || perr.Hop != 2 || perr.Pointer != 0x9004 {
+		t.Errorf("ReadInt32 = %v, want a PointerError at hop 2", err)
+	}
+}
@@ -1,15 +1,87 @@
 package memory
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
-type ReadError []error
+// FieldError is why Read could not read a field.
+type FieldError struct {
+	Struct string
+	Field  string
+	// Expr is the expression of the field as written in its tag
+	Expr string
+	// Hop is the number of pointers that were followed before the read
+	// that failed. It equals the length of the pointer chain if the value
+	// itself could not be read. Elements of struct slices count from the
+	// element.
+	Hop  int
+	Addr int64
+	// Optional is set for fields tagged optional
+	Optional bool
+	// Err is the cause, errors.Is tells apart ErrUnmapped,
+	// ErrProcessExited, ErrImplausible, the length errors of strings and
+	// arrays and the errors of the reader.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("failed to read %s.%s (%s, hop %d at 0x%x): %v",
+		e.Struct, e.Field, e.Expr, e.Hop, e.Addr, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ReadError lists the fields Read could not read. Fields tagged optional
+// are kept apart from the others.
+type ReadError struct {
+	Required []*FieldError
+	Optional []*FieldError
+}
 
 func (r ReadError) Error() string {
 	var strs []string
 
-	for _, err := range r {
-		strs = append(strs, err.Error())
+	for _, err := range r.Fields() {
+		if err.Optional {
+			strs = append(strs, err.Error()+" (optional)")
+		} else {
+			strs = append(strs, err.Error())
+		}
 	}
 
 	return strings.Join(strs, ", ")
 }
+
+func (r *ReadError) add(err *FieldError) {
+	if err.Optional {
+		r.Optional = append(r.Optional, err)
+	} else {
+		r.Required = append(r.Required, err)
+	}
+}
+
+// Fields returns every field that failed, required fields first.
+func (r ReadError) Fields() []*FieldError {
+	return append(append([]*FieldError(nil), r.Required...), r.Optional...)
+}
+
+func (r ReadError) Unwrap() []error {
+	var errs []error
+
+	for _, err := range r.Fields() {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// OnlyOptional reports whether err is a ReadError in which only optional
+// fields failed, so the data that was read can still be used.
+func OnlyOptional(err error) bool {
+	var rerr ReadError
+	return errors.As(err, &rerr) && len(rerr.Required) == 0
+}
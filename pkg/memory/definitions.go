@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// DefinitionsVersion is the version of the definitions file format
+// LoadDefinitions reads.
+const DefinitionsVersion = 1
+
+var ErrDefinitionsVersion = errors.New("unsupported definitions version")
+
+// Definitions replace the sig and memory tags of the Go structs, so moved
+// offsets can be fixed by editing a file instead of shipping a new binary.
+// Everything that is not defined keeps the tag of the struct.
+//
+//	{
+//		"version": 1,
+//		"game": "b20240123",
+//		"signatures": {"Base": "F8 01 74 04 83 65"},
+//		"variables": {"Ruleset": "[[Rulesets - 0xB] + 0x4]"},
+//		"fields": {
+//			"gameplayD": {"Score.HitMiss": "Self + 0x92", "LeaderBoard": "[Ruleset + 0x7C] + 0x24,optional"}
+//		}
+//	}
+type Definitions struct {
+	Version int `json:"version"`
+
+	// Game is the osu! version the definitions were written for, it is only
+	// shown to people.
+	Game string `json:"game,omitempty"`
+
+	// Signatures replace the sig tags of the int64 fields of the addresses
+	// struct with the same names, see ScanOptions.Signatures.
+	Signatures map[string]string `json:"signatures,omitempty"`
+
+	// Variables are expressions the memory tags can refer to by name. They
+	// take precedence over the methods and fields of the addresses struct.
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// Fields replace the memory tags, options included, by the name of the
+	// struct type and the path of the field in it. Fields of nested structs
+	// are named like Score.HitMiss, elements of struct slices are defined
+	// by the name of their own type. Only struct types a plan is compiled
+	// for can be defined, see CheckFields.
+	Fields map[string]map[string]string `json:"fields,omitempty"`
+}
+
+// LoadDefinitions reads a definitions file written in JSON.
+func LoadDefinitions(path string) (*Definitions, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs Definitions
+	if err := json.Unmarshal(b, &defs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if defs.Version != DefinitionsVersion {
+		return nil, fmt.Errorf("%w: %s is version %d, only %d is supported",
+			ErrDefinitionsVersion, path, defs.Version, DefinitionsVersion)
+	}
+
+	return &defs, nil
+}
+
+// variable returns the expression defined for name.
+func (d *Definitions) variable(name string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+
+	expr, ok := d.Variables[name]
+	return expr, ok
+}
+
+// field returns the memory tag defined for the field at path of the struct
+// type named structName.
+func (d *Definitions) field(structName, path string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+
+	tag, ok := d.Fields[structName][path]
+	return tag, ok
+}
+
+// CheckFields fails if Fields defines a struct type none of plans read, like
+// a misspelled one or a nested struct, whose fields are defined through the
+// struct containing it like Score.HitMiss. Pass every plan compiled with d.
+func (d *Definitions) CheckFields(plans ...*Plan) error {
+	if d == nil {
+		return nil
+	}
+
+	read := map[string]bool{}
+	for _, plan := range plans {
+		plan.structNames(read)
+	}
+
+	var names []string
+	for name := range d.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !read[name] {
+			return fmt.Errorf("definitions: no struct named %s is read, nested structs are defined through their parent", name)
+		}
+	}
+
+	return nil
+}
+
+// CheckSignatures fails if Signatures defines a name that is no int64 field
+// of addresses, a struct or a pointer to one, or of the structs embedded in
+// it. ResolvePatterns would ignore it.
+func (d *Definitions) CheckSignatures(addresses interface{}) error {
+	if d == nil {
+		return nil
+	}
+
+	t := structType(addresses)
+	if t == nil {
+		panic("addresses must be a struct or a pointer to a struct")
+	}
+
+	var names []string
+	for name := range d.Signatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if f, ok := t.FieldByName(name); !ok || f.Type.Kind() != reflect.Int64 {
+			return fmt.Errorf("definitions: %s has no int64 field %s to resolve the signature into", t.Name(), name)
+		}
+	}
+
+	return nil
+}
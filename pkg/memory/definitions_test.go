@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testInnerAddresses struct {
+	Inner int64
+}
+
+func (testInnerAddresses) InnerPlus() string {
+	return "Inner + 0x10"
+}
+
+type testAddresses struct {
+	testInnerAddresses
+	Base  int64
+	Other int32
+}
+
+func (testAddresses) Deref() string {
+	return "[Base + 0x4]"
+}
+
+func (testAddresses) Broken() string {
+	return "[Missing + 0x4]"
+}
+
+func TestVariables(t *testing.T) {
+	want := []string{"Base", "Deref", "Inner", "InnerPlus"}
+	if got := Variables(&testAddresses{}, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables = %q, want %q", got, want)
+	}
+
+	defs := &Definitions{Variables: map[string]string{
+		"Missing": "Base + 0x20",
+		"Extra":   "[Deref]",
+	}}
+
+	want = []string{"Base", "Broken", "Deref", "Extra", "Inner", "InnerPlus", "Missing"}
+	if got := Variables(testAddresses{}, defs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables with definitions = %q, want %q", got, want)
+	}
+}
+
+func TestEvaluateDefinitions(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x100)
+	p.putUint32(0x1004, 0x1040)
+	p.putUint32(0x1024, 0x1080)
+	p.putUint32(0x1040, 0x10c0)
+
+	addrs := &testAddresses{testInnerAddresses{Inner: 0x2000}, 0x1000, 0}
+	defs := &Definitions{Variables: map[string]string{
+		"Missing": "Base + 0x20",
+		"Deref":   "[Base + 0x24]",
+	}}
+
+	tests := []struct {
+		expr string
+		defs *Definitions
+		want int64
+	}{
+		{"InnerPlus", nil, 0x2010},
+		{"Deref", nil, 0x1040},
+		{"[Deref]", nil, 0x10c0},
+		{"Deref", defs, 0x1080},
+		{"Broken", defs, 0x1080},
+	}
+
+	for _, tt := range tests {
+		got, err := EvaluateDefinitions(p, addrs, tt.expr, tt.defs)
+		if err != nil || got != tt.want {
+			t.Errorf("EvaluateDefinitions(%q) = 0x%x, %v, want 0x%x", tt.expr, got, err, tt.want)
+		}
+	}
+
+	if _, err := Evaluate(p, addrs, "Broken"); err == nil {
+		t.Error("Evaluate(Broken) without definitions succeeded")
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	plan, err := Compile(&planAddresses{}, &nestedData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"nestedData", true},
+		// elements of struct slices are defined by their own type
+		{"nestedItem", true},
+		// nested structs are defined through their parent
+		{"Score", false},
+		{"planData", false},
+	}
+
+	for _, tt := range tests {
+		defs := &Definitions{Fields: map[string]map[string]string{tt.name: {}}}
+		if err := defs.CheckFields(plan); (err == nil) != tt.ok {
+			t.Errorf("CheckFields with %s = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+
+	var defs *Definitions
+	if err := defs.CheckFields(plan); err != nil {
+		t.Errorf("CheckFields without definitions = %v", err)
+	}
+}
@@ -0,0 +1,397 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+)
+
+// mem is a parsed memory expression, the sum of Offset and its terms.
+//
+//	[[Ruleset + 0x68] + 0x38] + 0x92
+//
+// is the offset 0x92 plus the pointer read at [Ruleset + 0x68] + 0x38.
+//
+// The grammar of an expression is
+//
+//	sum     = product { ("+" | "-") product }
+//	product = factor { "*" factor }
+//	factor  = "-" factor | "[" [ "?" ] [ width ":" ] sum "]" | "(" sum ")" | name | int
+//	width   = "u8" | "u16" | "u32" | "u64" | "i8" | "i16" | "i32" | "i64"
+//
+// A dereference reads a pointer of the size of the process' layout unless a
+// width is given. "[?X]" is null safe: if the pointer at X is null or can't
+// be read the whole expression is null and the field is left at zero
+// without an error. Names containing a dot, like osu!.exe, are the base
+// addresses of modules, other names are variables.
+type mem struct {
+	Offset int64
+	Terms  []memTerm
+}
+
+// memTerm is Scale times a dereference, a module or a variable. Exactly one
+// of Deref, Module and Var is set.
+type memTerm struct {
+	Scale int64
+
+	Deref *mem
+	Width string
+	Null  bool
+
+	Module string
+	Var    string
+}
+
+// derefWidths are the widths a dereference can have, in bytes and whether
+// they are sign extended.
+var derefWidths = map[string]struct {
+	size   int
+	signed bool
+}{
+	"u8":  {1, false},
+	"u16": {2, false},
+	"u32": {4, false},
+	"u64": {8, false},
+	"i8":  {1, true},
+	"i16": {2, true},
+	"i32": {4, true},
+	"i64": {8, true},
+}
+
+func (m *mem) String() string {
+	var b strings.Builder
+
+	for i, t := range m.Terms {
+		switch {
+		case i == 0 && t.Scale < 0:
+			b.WriteString("-")
+		case i > 0 && t.Scale < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+
+		if t.Scale != 1 && t.Scale != -1 {
+			scale := t.Scale
+			if scale < 0 {
+				scale = -scale
+			}
+			_, _ = fmt.Fprintf(&b, "0x%x * ", scale)
+		}
+
+		b.WriteString(t.String())
+	}
+
+	switch {
+	case len(m.Terms) == 0:
+		_, _ = fmt.Fprintf(&b, "0x%x", m.Offset)
+	case m.Offset > 0:
+		_, _ = fmt.Fprintf(&b, " + 0x%x", m.Offset)
+	case m.Offset < 0:
+		_, _ = fmt.Fprintf(&b, " - 0x%x", -m.Offset)
+	}
+
+	return b.String()
+}
+
+// String returns the term without its scale.
+func (t memTerm) String() string {
+	switch {
+	case t.Deref != nil:
+		var b strings.Builder
+
+		b.WriteString("[")
+		if t.Null {
+			b.WriteString("?")
+		}
+		if t.Width != "" {
+			b.WriteString(t.Width + ": ")
+		}
+		b.WriteString(t.Deref.String())
+		b.WriteString("]")
+
+		return b.String()
+	case t.Module != "":
+		return t.Module
+	default:
+		return t.Var
+	}
+}
+
+// add adds scale * o to m.
+func (m *mem) add(o *mem, scale int64) {
+	m.Offset += scale * o.Offset
+
+	for _, t := range o.Terms {
+		t.Scale *= scale
+		m.Terms = append(m.Terms, t)
+	}
+}
+
+// expand replaces variables for which lookup returns an expression by that
+// expression. lookup returns nil for variables that stay variables.
+func (m *mem) expand(lookup func(name string) (*mem, error)) (*mem, error) {
+	out := &mem{Offset: m.Offset}
+
+	for _, t := range m.Terms {
+		switch {
+		case t.Deref != nil:
+			inner, err := t.Deref.expand(lookup)
+			if err != nil {
+				return nil, err
+			}
+
+			t.Deref = inner
+			out.Terms = append(out.Terms, t)
+		case t.Module != "":
+			out.Terms = append(out.Terms, t)
+		default:
+			sub, err := lookup(t.Var)
+			if err != nil {
+				return nil, err
+			}
+
+			if sub == nil {
+				out.Terms = append(out.Terms, t)
+				continue
+			}
+
+			out.add(sub, t.Scale)
+		}
+	}
+
+	return out, nil
+}
+
+// isNameRune allows the characters of module file names like osu!.exe in
+// names.
+func isNameRune(ch rune, i int) bool {
+	return ch == '_' || unicode.IsLetter(ch) ||
+		(i > 0 && (unicode.IsDigit(ch) || ch == '!' || ch == '.'))
+}
+
+// memToken is a token of an expression and the column it starts at.
+type memToken struct {
+	tok    rune
+	text   string
+	column int
+}
+
+// memParser parses one expression, see mem. ahead is the token after the
+// current one once peek scanned it.
+type memParser struct {
+	s scanner.Scanner
+	memToken
+	ahead *memToken
+}
+
+func parseMem(tag string) (*mem, error) {
+	p := &memParser{}
+	p.s.Init(strings.NewReader(tag))
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts
+	p.s.IsIdentRune = isNameRune
+	p.s.Error = func(*scanner.Scanner, string) {}
+	p.next()
+
+	expr, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok != scanner.EOF {
+		return nil, p.unexpected()
+	}
+
+	return expr, nil
+}
+
+func (p *memParser) next() {
+	if p.ahead != nil {
+		p.memToken, p.ahead = *p.ahead, nil
+		return
+	}
+
+	p.memToken = p.scan()
+}
+
+// peek returns the token after the current one without moving on.
+func (p *memParser) peek() memToken {
+	if p.ahead == nil {
+		t := p.scan()
+		p.ahead = &t
+	}
+
+	return *p.ahead
+}
+
+func (p *memParser) scan() memToken {
+	t := memToken{tok: p.s.Scan(), text: p.s.TokenText(), column: p.s.Position.Column}
+	if t.tok == scanner.EOF {
+		t.column = p.s.Pos().Column
+	}
+
+	return t
+}
+
+// errorf returns an error at the column of the current token.
+func (p *memParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", p.column, fmt.Sprintf(format, args...))
+}
+
+// token describes the current token for errors.
+func (p *memParser) token() string {
+	if p.tok == scanner.EOF {
+		return "end of expression"
+	}
+
+	return strconv.Quote(p.text)
+}
+
+func (p *memParser) unexpected() error {
+	return p.errorf("unexpected %s", p.token())
+}
+
+func (p *memParser) expect(tok rune) error {
+	if p.tok != tok {
+		return p.errorf("unexpected %s, expected %s", p.token(), scanner.TokenString(tok))
+	}
+
+	p.next()
+	return nil
+}
+
+func (p *memParser) sum() (*mem, error) {
+	expr, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok == '+' || p.tok == '-' {
+		scale := int64(1)
+		if p.tok == '-' {
+			scale = -1
+		}
+		p.next()
+
+		rest, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+
+		expr.add(rest, scale)
+	}
+
+	return expr, nil
+}
+
+func (p *memParser) product() (*mem, error) {
+	expr, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok == '*' {
+		col := p.column
+		p.next()
+
+		rhs, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(rhs.Terms) == 0:
+			scaled := &mem{}
+			scaled.add(expr, rhs.Offset)
+			expr = scaled
+		case len(expr.Terms) == 0:
+			scaled := &mem{}
+			scaled.add(rhs, expr.Offset)
+			expr = scaled
+		default:
+			return nil, fmt.Errorf("column %d: only a constant can be multiplied", col)
+		}
+	}
+
+	return expr, nil
+}
+
+func (p *memParser) factor() (*mem, error) {
+	switch p.tok {
+	case '[':
+		p.next()
+		return p.deref()
+	case '-':
+		p.next()
+
+		expr, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		neg := &mem{}
+		neg.add(expr, -1)
+		return neg, nil
+	case '(':
+		p.next()
+
+		expr, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+
+		return expr, p.expect(')')
+	case scanner.Ident:
+		name := p.text
+		p.next()
+
+		if strings.Contains(name, ".") {
+			return &mem{Terms: []memTerm{{Scale: 1, Module: name}}}, nil
+		}
+
+		return &mem{Terms: []memTerm{{Scale: 1, Var: name}}}, nil
+	case scanner.Int:
+		n, err := strconv.ParseInt(p.text, 0, 64)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.next()
+
+		return &mem{Offset: n}, nil
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+// deref parses a dereference after its "[".
+func (p *memParser) deref() (*mem, error) {
+	t := memTerm{Scale: 1}
+
+	if p.tok == '?' {
+		t.Null = true
+		p.next()
+	}
+
+	// a width is a name followed by ":", anything else is part of the sum
+	if p.tok == scanner.Ident {
+		if _, ok := derefWidths[p.text]; ok && p.peek().tok == ':' {
+			t.Width = p.text
+			p.next()
+			p.next()
+		}
+	}
+
+	inner, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+
+	t.Deref = inner
+	return &mem{Terms: []memTerm{t}}, nil
+}
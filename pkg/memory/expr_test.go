@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMem(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"Base", "Base"},
+		{"Base - 0x33", "Base - 0x33"},
+		{"a - b + c", "a - b + c"},
+		{"a - (b + c)", "a - b - c"},
+		{"a\t-   b", "a - b"},
+		{"-X", "-X"},
+		{"12", "0xc"},
+
+		{"[Base - 0xC]", "[Base - 0xc]"},
+		{"[[Rulesets - 0xB] + 0x4]", "[[Rulesets - 0xb] + 0x4]"},
+		{"[u32: X]", "[u32: X]"},
+		{"[u32 : X]", "[u32: X]"},
+		{"[ u32:X ]", "[u32: X]"},
+		{"[i16: X] - 0x10", "[i16: X] - 0x10"},
+		{"[?X]", "[?X]"},
+		{"[? u8 : X + 4]", "[?u8: X + 0x4]"},
+		{"[?[X] + 8] + 2", "[?[X] + 0x8] + 0x2"},
+
+		// width names are only widths when followed by ":"
+		{"u32", "u32"},
+		{"[u32]", "[u32]"},
+		{"[u32 + 1]", "[u32 + 0x1]"},
+
+		{"2 * X", "0x2 * X"},
+		{"X * 2", "0x2 * X"},
+		{"2 * 3", "0x6"},
+		{"4 * (X - 1)", "0x4 * X - 0x4"},
+		{"[X] * 2", "0x2 * [X]"},
+
+		{"osu!.exe + 0x10", "osu!.exe + 0x10"},
+		{"Self + 0x94", "Self + 0x94"},
+	}
+
+	for _, tt := range tests {
+		m, err := parseMem(tt.expr)
+		if err != nil {
+			t.Errorf("parseMem(%q): %v", tt.expr, err)
+			continue
+		}
+
+		if got := m.String(); got != tt.want {
+			t.Errorf("parseMem(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseMemTerms(t *testing.T) {
+	tests := []struct {
+		expr string
+		want memTerm
+	}{
+		{"osu!.exe", memTerm{Scale: 1, Module: "osu!.exe"}},
+		{"user32.dll", memTerm{Scale: 1, Module: "user32.dll"}},
+		{"osu!", memTerm{Scale: 1, Var: "osu!"}},
+		{"Base", memTerm{Scale: 1, Var: "Base"}},
+		{"-3 * Base", memTerm{Scale: -3, Var: "Base"}},
+	}
+
+	for _, tt := range tests {
+		m, err := parseMem(tt.expr)
+		if err != nil {
+			t.Errorf("parseMem(%q): %v", tt.expr, err)
+			continue
+		}
+
+		if len(m.Terms) != 1 || !reflect.DeepEqual(m.Terms[0], tt.want) {
+			t.Errorf("parseMem(%q) terms %+v, want [%+v]", tt.expr, m.Terms, tt.want)
+		}
+	}
+
+	m, err := parseMem("[?i8: Base]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := m.Terms[0]; !d.Null || d.Width != "i8" || d.Deref == nil || d.Deref.String() != "Base" {
+		t.Errorf("parseMem(%q) deref %+v", "[?i8: Base]", d)
+	}
+}
+
+func TestParseMemErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "column 1: unexpected end of expression"},
+		{"X +", "column 4: unexpected end of expression"},
+		{"X ]", `column 3: unexpected "]"`},
+		{"X $ Y", `column 3: unexpected "$"`},
+		{"[X", `column 3: unexpected end of expression, expected "]"`},
+		{"[u32: X", `column 8: unexpected end of expression, expected "]"`},
+		{"(X", `column 3: unexpected end of expression, expected ")"`},
+		{"[u32:]", `column 6: unexpected "]"`},
+		{"X * Y", "column 3: only a constant can be multiplied"},
+		{"[X * Y]", "column 4: only a constant can be multiplied"},
+		{"[X] * [Y]", "column 5: only a constant can be multiplied"},
+		{"0x", `column 1: strconv.ParseInt: parsing "0x": invalid syntax`},
+		{"Base + 99999999999999999999", `column 8: strconv.ParseInt: parsing "99999999999999999999": value out of range`},
+	}
+
+	for _, tt := range tests {
+		_, err := parseMem(tt.expr)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseMem(%q) = %v, want %s", tt.expr, err, tt.err)
+		}
+	}
+}
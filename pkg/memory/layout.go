@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Layout describes the pointer size of a process and where the .NET runtime
+// puts the fields of the managed objects Read* understands. Offsets are
+// relative to the start of the object, which begins with its method table
+// pointer.
+type Layout struct {
+	PointerSize int
+
+	// System.String
+	StringLength int64
+	StringData   int64
+
+	// System.Collections.Generic.List<T>, the fields the array helpers read
+	ListItems int64
+	ListSize  int64
+
+	// T[], the backing array of a List<T>
+	ArrayLength int64
+	ArrayData   int64
+}
+
+var (
+	// Layout32 is the layout of 32-bit .NET Framework processes like osu!
+	// stable. It is used for readers that don't provide a layout.
+	Layout32 = Layout{
+		PointerSize:  4,
+		StringLength: 4,
+		StringData:   8,
+		ListItems:    4,
+		ListSize:     12,
+		ArrayLength:  4,
+		ArrayData:    8,
+	}
+
+	// Layout64 is the layout of 64-bit .NET Core processes like osu!lazer.
+	// .NET Core dropped the _syncRoot field of List<T>, so _size directly
+	// follows _items.
+	Layout64 = Layout{
+		PointerSize:  8,
+		StringLength: 8,
+		StringData:   12,
+		ListItems:    8,
+		ListSize:     16,
+		ArrayLength:  8,
+		ArrayData:    16,
+	}
+)
+
+var ErrUnknownLayout = errors.New("cannot tell the pointer size of the process")
+
+// layouter is implemented by readers that know the layout of their process.
+type layouter interface {
+	Layout() Layout
+}
+
+// layoutOf returns the layout of r, Layout32 if r doesn't know.
+func layoutOf(r io.ReaderAt) Layout {
+	if l, ok := r.(layouter); ok {
+		return l.Layout()
+	}
+
+	return Layout32
+}
+
+// WithLayout returns a Process that reads p with layout l. Everything else
+// is forwarded to p, closing it closes p.
+func WithLayout(p Process, l Layout) Process {
+	return &layoutProcess{Process: p, layout: l}
+}
+
+type layoutProcess struct {
+	Process
+	layout Layout
+}
+
+func (p *layoutProcess) Layout() Layout {
+	return p.layout
+}
+
+func (p *layoutProcess) Alive() bool {
+	return isAlive(p.Process)
+}
+
+func (p *layoutProcess) StartTime() (time.Time, error) {
+	if s, ok := p.Process.(started); ok {
+		return s.StartTime()
+	}
+
+	return time.Time{}, errors.New("process start time unknown")
+}
+
+// PE header values DetectLayout looks at.
+const (
+	peMachineI386  = 0x14c
+	peMachineAMD64 = 0x8664
+	peMachineARM64 = 0xaa64
+)
+
+// DetectLayout reads the PE header of the executable image of p to tell
+// 32-bit from 64-bit processes. It works for anything that maps the image,
+// snapshots and minidumps included.
+func DetectLayout(p Process) (Layout, error) {
+	exe, err := p.ExecutablePath()
+	if err != nil {
+		return Layout{}, err
+	}
+
+	maps, err := p.Maps()
+	if err != nil {
+		return Layout{}, err
+	}
+
+	for _, m := range maps {
+		if m.Type() != TypeImage || !strings.EqualFold(moduleName(m.Module()), moduleName(exe)) {
+			continue
+		}
+
+		machine, err := peMachine(p, m.Start())
+		if err != nil {
+			continue
+		}
+
+		switch machine {
+		case peMachineI386:
+			return Layout32, nil
+		case peMachineAMD64, peMachineARM64:
+			return Layout64, nil
+		default:
+			return Layout{}, fmt.Errorf("%w: PE machine 0x%x", ErrUnknownLayout, machine)
+		}
+	}
+
+	return Layout{}, ErrUnknownLayout
+}
+
+// peMachine reads the Machine field of the PE image mapped at base.
+func peMachine(r io.ReaderAt, base int64) (uint16, error) {
+	var dos [64]byte
+	if _, err := readFullAt(r, dos[:], base); err != nil {
+		return 0, err
+	}
+
+	if dos[0] != 'M' || dos[1] != 'Z' {
+		return 0, errors.New("no MZ header")
+	}
+
+	var nt [6]byte
+	lfanew := int64(binary.LittleEndian.Uint32(dos[0x3c:]))
+	if _, err := readFullAt(r, nt[:], base+lfanew); err != nil {
+		return 0, err
+	}
+
+	if string(nt[:4]) != "PE\x00\x00" {
+		return 0, errors.New("no PE header")
+	}
+
+	return binary.LittleEndian.Uint16(nt[4:]), nil
+}
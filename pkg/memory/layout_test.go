@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// mapImage maps a PE image of exe at base whose header names machine.
+func mapImage(p *fakeProcess, base int64, machine uint16) {
+	reg := p.mapRegion(base, 0x1000)
+	reg.prot = ProtRead
+	reg.type_ = TypeImage
+	reg.module = p.exe
+
+	p.write(base, []byte("MZ"))
+	p.putUint32(base+0x3c, 0x80)
+	p.write(base+0x80, []byte("PE\x00\x00"))
+	p.write(base+0x84, binary.LittleEndian.AppendUint16(nil, machine))
+}
+
+func TestDetectLayout(t *testing.T) {
+	tests := []struct {
+		machine uint16
+		want    Layout
+	}{
+		{peMachineI386, Layout32},
+		{peMachineAMD64, Layout64},
+		{peMachineARM64, Layout64},
+	}
+
+	for _, tt := range tests {
+		p := newFakeProcess(Layout{})
+		p.mapRegion(0x10000, 0x1000)
+		mapImage(p, 0x400000, tt.machine)
+
+		got, err := DetectLayout(p)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("machine 0x%x: DetectLayout = %+v, %v, want %+v", tt.machine, got, err, tt.want)
+		}
+	}
+}
+
+func TestDetectLayoutUnknown(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	mapImage(p, 0x400000, 0x1c0)
+
+	if _, err := DetectLayout(p); !errors.Is(err, ErrUnknownLayout) {
+		t.Errorf("unknown machine: DetectLayout = %v, want ErrUnknownLayout", err)
+	}
+
+	p = newFakeProcess(Layout{})
+	p.mapRegion(0x400000, 0x1000)
+
+	if _, err := DetectLayout(p); !errors.Is(err, ErrUnknownLayout) {
+		t.Errorf("no image: DetectLayout = %v, want ErrUnknownLayout", err)
+	}
+}
+
+// TestLayoutObjects reads a string and a List<int> laid out the way each
+// runtime lays them out.
+func TestLayoutObjects(t *testing.T) {
+	for _, layout := range []Layout{Layout32, Layout64} {
+		p := newFakeProcess(layout)
+		p.mapRegion(0x1000, 0x1000)
+
+		const str, list, array = 0x1100, 0x1200, 0x1300
+
+		p.putUint32(str+layout.StringLength, 2)
+		p.write(str+layout.StringData, []byte{'h', 0, 'i', 0})
+
+		p.putPtr(list+layout.ListItems, array)
+		p.putUint32(list+layout.ListSize, 3)
+		// _version follows _size, reading it as the length would give 99
+		p.putUint32(list+layout.ListSize+4, 99)
+		p.putUint32(array+layout.ArrayLength, 4)
+		for i, v := range []uint32{7, 8, 9, 10} {
+			p.putUint32(array+layout.ArrayData+int64(4*i), v)
+		}
+
+		s, err := ReadString(p, str)
+		if err != nil || s != "hi" {
+			t.Errorf("pointer size %d: ReadString = %q, %v", layout.PointerSize, s, err)
+		}
+
+		a, err := ReadInt32Array(p, list)
+		if err != nil || !reflect.DeepEqual(a, []int32{7, 8, 9}) {
+			t.Errorf("pointer size %d: ReadInt32Array = %v, %v", layout.PointerSize, a, err)
+		}
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+	p.putUint64(0x1000, 0x1122334455667788)
+
+	if got, _ := ReadPtr(p, 0x1000); got != 0x55667788 {
+		t.Errorf("Layout32 ReadPtr = 0x%x", got)
+	}
+
+	if got, _ := ReadPtr(WithLayout(p, Layout64), 0x1000); got != 0x1122334455667788 {
+		t.Errorf("Layout64 ReadPtr = 0x%x", got)
+	}
+}
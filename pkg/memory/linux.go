@@ -0,0 +1,398 @@
+//go:build linux
+// +build linux
+
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// readCmdline returns the NUL separated arguments of a process.
+func readCmdline(pid int) ([]string, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	b = bytes.TrimRight(b, "\x00")
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(string(b), "\x00"), nil
+}
+
+func readComm(pid int) (string, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readEnv looks up a single variable in the environment the process was
+// started with.
+func readEnv(pid int, key string) (string, bool) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return "", false
+	}
+
+	for _, kv := range strings.Split(string(b), "\x00") {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// isWindowsPath reports whether s looks like a drive letter path (C:\...)
+// as Wine passes it in argv[0].
+func isWindowsPath(s string) bool {
+	return len(s) >= 3 && s[1] == ':' && (s[2] == '\\' || s[2] == '/')
+}
+
+// winePath translates a Windows path of a Wine process into the host path
+// using the dosdevices links of its prefix.
+func winePath(pid int, path string) (string, error) {
+	prefix, ok := readEnv(pid, "WINEPREFIX")
+	if !ok {
+		home, ok := readEnv(pid, "HOME")
+		if !ok {
+			return "", fmt.Errorf("cannot determine wine prefix of process %d", pid)
+		}
+		prefix = filepath.Join(home, ".wine")
+	}
+
+	return dosDevicePath(prefix, path), nil
+}
+
+// dosDevicePath returns the path of a drive letter path in the dosdevices
+// of the Wine prefix, C:\osu!\osu!.exe is prefix/dosdevices/c:/osu!/osu!.exe.
+func dosDevicePath(prefix, path string) string {
+	drive := strings.ToLower(path[:2])
+	rest := strings.ReplaceAll(path[3:], "\\", "/")
+
+	return filepath.Join(prefix, "dosdevices", drive, rest)
+}
+
+// FindProcess finds processes whose argv[0] or comm name matches re. Under
+// Wine argv[0] is the Windows path of the executable and comm is its file
+// name, so the same expressions as on Windows work.
+//
+// Window titles cannot be queried without talking to the display server, so
+// blacklistedTitles are matched against the command line instead.
+func FindProcess(re *regexp.Regexp, blacklistedTitles ...string) ([]Process, error) {
+	var processes []Process
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+
+		args, err := readCmdline(pid)
+		if err != nil || len(args) == 0 {
+			continue
+		}
+
+		comm, err := readComm(pid)
+		if err != nil {
+			continue
+		}
+
+		if !re.MatchString(args[0]) && !re.MatchString(comm) {
+			continue
+		}
+
+		cmdline := strings.Join(args, " ")
+
+		var isBanned = false
+		for _, title := range blacklistedTitles {
+			if strings.Contains(cmdline, title) {
+				isBanned = true
+				break
+			}
+		}
+
+		if !isBanned {
+			processes = append(processes, process{pid})
+		}
+	}
+
+	if len(processes) < 1 {
+		return nil, ErrNoProcess
+	}
+
+	return processes, nil
+}
+
+type process struct {
+	pid int
+}
+
+func (p process) ExecutablePath() (string, error) {
+	args, err := readCmdline(p.pid)
+	if err != nil {
+		return "", err
+	}
+
+	if len(args) > 0 && isWindowsPath(args[0]) {
+		return winePath(p.pid, args[0])
+	}
+
+	return os.Readlink(fmt.Sprintf("/proc/%d/exe", p.pid))
+}
+
+func (p process) Close() error {
+	return nil
+}
+
+func (p process) Pid() int {
+	return p.pid
+}
+
+func (p process) ReadAt(b []byte, off int64) (n int, err error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	local := []unix.Iovec{{Base: (*byte)(unsafe.Pointer(&b[0]))}}
+	local[0].SetLen(len(b))
+
+	remote := []unix.RemoteIovec{{Base: uintptr(off), Len: len(b)}}
+
+	n, err = unix.ProcessVMReadv(p.pid, local, remote, 0)
+	if n < 0 {
+		n = 0
+	}
+
+	switch {
+	case errors.Is(err, unix.EFAULT), errors.Is(err, unix.EIO):
+		err = fmt.Errorf("reading 0x%x: %w (%w)", off+int64(n), ErrUnmapped, err)
+	case errors.Is(err, unix.ESRCH):
+		err = fmt.Errorf("reading 0x%x: %w (%w)", off+int64(n), ErrProcessExited, err)
+	case err == nil && n < len(b):
+		// process_vm_readv stops at the first page it can't read
+		err = fmt.Errorf("reading 0x%x: %w", off+int64(n), ErrUnmapped)
+	}
+
+	return n, err
+}
+
+func (p process) Maps() ([]Map, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/maps", p.pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var maps []Map
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		reg, err := parseRegion(s.Text())
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, reg)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return maps, nil
+}
+
+// parseRegion parses a single line of /proc/<pid>/maps:
+//
+//	7f0000000000-7f0000001000 r-xp 00000000 08:01 1234    /usr/lib/libc.so.6
+func parseRegion(line string) (region, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return region{}, fmt.Errorf("malformed maps line: %q", line)
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return region{}, fmt.Errorf("malformed maps range: %q", fields[0])
+	}
+
+	var reg region
+	var err error
+
+	if reg.start, err = strconv.ParseUint(start, 16, 64); err != nil {
+		return region{}, err
+	}
+
+	if reg.end, err = strconv.ParseUint(end, 16, 64); err != nil {
+		return region{}, err
+	}
+
+	if reg.offset, err = strconv.ParseUint(fields[2], 16, 64); err != nil {
+		return region{}, err
+	}
+
+	reg.perms = fields[1]
+
+	if len(fields) > 5 {
+		reg.path = strings.Join(fields[5:], " ")
+	}
+
+	return reg, nil
+}
+
+type region struct {
+	start  uint64
+	end    uint64
+	perms  string
+	offset uint64
+	path   string
+}
+
+func (r region) Start() int64 {
+	return int64(r.start)
+}
+
+func (r region) Size() int64 {
+	return int64(r.end - r.start)
+}
+
+func (r region) Protection() Protection {
+	var p Protection
+
+	if strings.HasPrefix(r.perms, "r") {
+		p |= ProtRead
+	}
+	if len(r.perms) > 1 && r.perms[1] == 'w' {
+		p |= ProtWrite
+	}
+	if len(r.perms) > 2 && r.perms[2] == 'x' {
+		p |= ProtExec
+	}
+
+	return p
+}
+
+// State is always committed, /proc/<pid>/maps only lists mapped memory.
+func (r region) State() RegionState {
+	return StateCommitted
+}
+
+// Type guesses the region type from the backing file. Wine maps PE images
+// from their files, so .exe and .dll mappings count as images just like
+// shared objects and executable file mappings.
+func (r region) Type() RegionType {
+	name := strings.ToLower(r.Module())
+
+	switch {
+	case name == "":
+		return TypePrivate
+	case r.Protection()&ProtExec != 0,
+		strings.HasSuffix(name, ".exe"),
+		strings.HasSuffix(name, ".dll"),
+		strings.HasSuffix(name, ".so"),
+		strings.Contains(name, ".so."):
+		return TypeImage
+	default:
+		return TypeMapped
+	}
+}
+
+// Module returns the mapped file, pseudo paths like [heap] or [stack] are
+// anonymous memory.
+func (r region) Module() string {
+	if strings.HasPrefix(r.path, "[") {
+		return ""
+	}
+
+	return strings.TrimSuffix(r.path, " (deleted)")
+}
+
+func (p process) Alive() bool {
+	err := unix.Kill(p.pid, 0)
+	return err == nil || err == unix.EPERM
+}
+
+// userHZ is the unit of the times in /proc/<pid>/stat, it is 100 on every
+// architecture Linux runs on.
+const userHZ = 100
+
+func (p process) StartTime() (time.Time, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", p.pid))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ticks, err := parseStartTicks(stat)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("stat of process %d: %w", p.pid, err)
+	}
+
+	boot, err := bootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return boot.Add(time.Duration(ticks) * time.Second / userHZ), nil
+}
+
+// parseStartTicks returns the starttime field of /proc/<pid>/stat, the
+// clock ticks after boot the process started at.
+func parseStartTicks(stat []byte) (int64, error) {
+	// the command name can contain spaces and parentheses, the other
+	// fields start after the last ')'
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, errors.New("malformed stat")
+	}
+
+	// starttime is field 22, the fields after ')' start at field 3
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 20 {
+		return 0, errors.New("malformed stat")
+	}
+
+	return strconv.ParseInt(fields[19], 10, 64)
+}
+
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if btime, ok := strings.CutPrefix(s.Text(), "btime "); ok {
+			sec, err := strconv.ParseInt(btime, 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+
+	return time.Time{}, errors.New("no btime in /proc/stat")
+}
@@ -0,0 +1,125 @@
+//go:build linux
+// +build linux
+
+package memory
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		line   string
+		start  int64
+		size   int64
+		prot   Protection
+		type_  RegionType
+		module string
+	}{
+		{"7f0000000000-7f0000021000 rw-p 00000000 00:00 0",
+			0x7f0000000000, 0x21000, ProtRead | ProtWrite, TypePrivate, ""},
+		{"55d0c0a00000-55d0c0a21000 rw-p 00000000 00:00 0                          [heap]",
+			0x55d0c0a00000, 0x21000, ProtRead | ProtWrite, TypePrivate, ""},
+		{"7ffd5a1e0000-7ffd5a201000 rw-p 00000000 00:00 0                          [stack]",
+			0x7ffd5a1e0000, 0x21000, ProtRead | ProtWrite, TypePrivate, ""},
+		{"7f1a2b000000-7f1a2b180000 r-xp 00028000 08:01 1311       /usr/lib/x86_64-linux-gnu/libc.so.6",
+			0x7f1a2b000000, 0x180000, ProtRead | ProtExec, TypeImage, "/usr/lib/x86_64-linux-gnu/libc.so.6"},
+		{"00400000-00401000 r--p 00000000 08:01 4242       /home/user/.wine/drive_c/osu!/osu!.exe",
+			0x400000, 0x1000, ProtRead, TypeImage, "/home/user/.wine/drive_c/osu!/osu!.exe"},
+		{"7f0000100000-7f0000200000 rw-s 00000000 00:05 77         /memfd:wine-mapping (deleted)",
+			0x7f0000100000, 0x100000, ProtRead | ProtWrite, TypeMapped, "/memfd:wine-mapping"},
+		{"7f0000300000-7f0000301000 r--p 00000000 08:01 99         /home/user/My Songs/font file.ttf",
+			0x7f0000300000, 0x1000, ProtRead, TypeMapped, "/home/user/My Songs/font file.ttf"},
+		{"7f0000400000-7f0000401000 ---p 00000000 00:00 0",
+			0x7f0000400000, 0x1000, 0, TypePrivate, ""},
+	}
+
+	for _, tt := range tests {
+		reg, err := parseRegion(tt.line)
+		if err != nil {
+			t.Errorf("parseRegion(%q): %v", tt.line, err)
+			continue
+		}
+
+		if reg.Start() != tt.start || reg.Size() != tt.size || reg.Protection() != tt.prot ||
+			reg.Type() != tt.type_ || reg.Module() != tt.module || reg.State() != StateCommitted {
+			t.Errorf("parseRegion(%q) = 0x%x+0x%x %s %s %q, want 0x%x+0x%x %s %s %q", tt.line,
+				reg.Start(), reg.Size(), reg.Protection(), reg.Type(), reg.Module(),
+				tt.start, tt.size, tt.prot, tt.type_, tt.module)
+		}
+	}
+
+	for _, line := range []string{
+		"",
+		"7f0000000000 rw-p 00000000 00:00 0",
+		"7f000000000g-7f0000021000 rw-p 00000000 00:00 0",
+		"7f0000000000-7f0000021000 rw-p xyz 00:00 0",
+	} {
+		if _, err := parseRegion(line); err == nil {
+			t.Errorf("parseRegion(%q) succeeded", line)
+		}
+	}
+}
+
+func TestParseStartTicks(t *testing.T) {
+	tests := []struct {
+		stat  string
+		ticks int64
+	}{
+		{"1234 (osu!.exe) S 1 1234 1234 0 -1 4194560 100 0 0 0 50 20 0 0 20 0 30 0 987654 123 45", 987654},
+		// the command name can hold spaces and parentheses
+		{"42 (a) b (c)) R 1 42 42 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 31337 0 0", 31337},
+	}
+
+	for _, tt := range tests {
+		if ticks, err := parseStartTicks([]byte(tt.stat)); err != nil || ticks != tt.ticks {
+			t.Errorf("parseStartTicks(%q) = %d, %v, want %d", tt.stat, ticks, err, tt.ticks)
+		}
+	}
+
+	for _, stat := range []string{"", "1234 osu!.exe S 1", "1234 (osu!.exe) S 1 1234", "1 (x) S 1 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 never 0 0"} {
+		if _, err := parseStartTicks([]byte(stat)); err == nil {
+			t.Errorf("parseStartTicks(%q) succeeded", stat)
+		}
+	}
+
+	started, err := process{os.Getpid()}.StartTime()
+	if err != nil || started.After(time.Now()) || time.Since(started) > time.Hour {
+		t.Errorf("StartTime of the test = %v, %v", started, err)
+	}
+}
+
+func TestDosDevicePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\osu!\osu!.exe`, "/home/user/.wine/dosdevices/c:/osu!/osu!.exe"},
+		{`Z:\home\user\games\osu!\osu!.exe`, "/home/user/.wine/dosdevices/z:/home/user/games/osu!/osu!.exe"},
+		{`c:/Program Files/osu!/osu!.exe`, "/home/user/.wine/dosdevices/c:/Program Files/osu!/osu!.exe"},
+		{`D:\`, "/home/user/.wine/dosdevices/d:"},
+	}
+
+	for _, tt := range tests {
+		if got := dosDevicePath("/home/user/.wine", tt.path); got != tt.want {
+			t.Errorf("dosDevicePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		path string
+		ok   bool
+	}{
+		{`C:\osu!\osu!.exe`, true},
+		{`Z:/osu!.exe`, true},
+		{`osu!.exe`, false},
+		{`/home/user/osu!.exe`, false},
+		{`C:`, false},
+	} {
+		if isWindowsPath(tt.path) != tt.ok {
+			t.Errorf("isWindowsPath(%q) = %v", tt.path, !tt.ok)
+		}
+	}
+}
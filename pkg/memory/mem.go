@@ -3,11 +3,17 @@ package memory
 import (
 	"errors"
 	"io"
+	"time"
 )
 
 var (
-	ErrNoProcess       = errors.New("no process matching the criteria was found")
-	ErrPatternNotFound = errors.New("no internal matched the pattern")
+	ErrNoProcess        = errors.New("no process matching the criteria was found")
+	ErrPatternNotFound  = errors.New("no internal matched the pattern")
+	ErrProcessExited    = errors.New("process has exited")
+	ErrAmbiguousPattern = errors.New("pattern matched more than one address")
+	ErrUnmapped         = errors.New("address is not mapped")
+	ErrModuleNotFound   = errors.New("module is not loaded")
+	ErrNoMaps           = errors.New("reader has no memory maps")
 )
 
 type (
@@ -19,8 +25,68 @@ type (
 		ExecutablePath() (string, error)
 	}
 
+	// MemoryUnmarshaler is implemented by field types that decode
+	// themselves when read by Read. addr is the address given by the tag
+	// of the field.
+	MemoryUnmarshaler interface {
+		UnmarshalMemory(r io.ReaderAt, addr int64) error
+	}
+
+	// alive is implemented by processes that can tell whether they are
+	// still running.
+	alive interface {
+		Alive() bool
+	}
+
+	// started is implemented by processes that know when they were
+	// started.
+	started interface {
+		StartTime() (time.Time, error)
+	}
+
+	// moduler is implemented by readers that look up the base addresses
+	// of modules themselves, usually to cache them.
+	moduler interface {
+		ModuleBase(name string) (int64, error)
+	}
+
+	// mapper is implemented by readers that know their memory maps, every
+	// Process does.
+	mapper interface {
+		Maps() ([]Map, error)
+	}
+
 	Map interface {
 		Start() int64
 		Size() int64
+		Protection() Protection
+		State() RegionState
+		Type() RegionType
+		// Module is the path of the file backing the region, empty for
+		// anonymous memory.
+		Module() string
 	}
 )
+
+// isAlive reports whether p is still running. Processes that can't tell,
+// like snapshots, are always alive.
+func isAlive(r io.ReaderAt) bool {
+	if a, ok := r.(alive); ok {
+		return a.Alive()
+	}
+
+	return true
+}
+
+// moduleBase returns the base address of the module name in r, see
+// ModuleBase.
+func moduleBase(r io.ReaderAt, name string) (int64, error) {
+	switch r := r.(type) {
+	case moduler:
+		return r.ModuleBase(name)
+	case mapper:
+		return ModuleBase(r, name)
+	default:
+		return 0, ErrNoMaps
+	}
+}
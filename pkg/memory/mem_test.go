@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// fakeProcess is an in-memory Process for tests. Reads of addresses outside
+// its regions fail with ErrUnmapped, reads after exit with ErrProcessExited
+// and reads starting at an address in fail with its error. Reads can run in
+// parallel, the regions must not change while they do.
+type fakeProcess struct {
+	mu sync.Mutex
+
+	pid     int
+	exe     string
+	layout  Layout
+	regions []fakeRegion
+	fail    map[int64]error
+	exited  bool
+	reads   int
+}
+
+type fakeRegion struct {
+	mapInfo
+	data []byte
+}
+
+func newFakeProcess(layout Layout) *fakeProcess {
+	return &fakeProcess{pid: 1234, exe: `C:\osu!\osu!.exe`, layout: layout}
+}
+
+// mapRegion maps size zeroed bytes at start as committed read-write private
+// memory and returns the region to adjust its attributes.
+func (p *fakeProcess) mapRegion(start, size int64) *fakeRegion {
+	p.regions = append(p.regions, fakeRegion{
+		mapInfo: mapInfo{
+			start: start,
+			size:  size,
+			prot:  ProtRead | ProtWrite,
+			state: StateCommitted,
+			type_: TypePrivate,
+		},
+		data: make([]byte, size),
+	})
+
+	sort.Slice(p.regions, func(i, j int) bool {
+		return p.regions[i].start < p.regions[j].start
+	})
+
+	for i := range p.regions {
+		if p.regions[i].start == start {
+			return &p.regions[i]
+		}
+	}
+
+	panic("unreachable")
+}
+
+// write copies b to addr, which must be mapped.
+func (p *fakeProcess) write(addr int64, b []byte) {
+	for i := range p.regions {
+		r := &p.regions[i]
+		if addr >= r.start && addr+int64(len(b)) <= r.start+r.size {
+			copy(r.data[addr-r.start:], b)
+			return
+		}
+	}
+
+	panic(fmt.Sprintf("write to unmapped 0x%x", addr))
+}
+
+func (p *fakeProcess) putUint32(addr int64, v uint32) {
+	p.write(addr, binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (p *fakeProcess) putUint64(addr int64, v uint64) {
+	p.write(addr, binary.LittleEndian.AppendUint64(nil, v))
+}
+
+// putPtr writes a pointer of the size of the layout of p.
+func (p *fakeProcess) putPtr(addr, v int64) {
+	if p.Layout().PointerSize == 8 {
+		p.putUint64(addr, uint64(v))
+	} else {
+		p.putUint32(addr, uint32(v))
+	}
+}
+
+func (p *fakeProcess) Close() error {
+	return nil
+}
+
+func (p *fakeProcess) Pid() int {
+	return p.pid
+}
+
+func (p *fakeProcess) ExecutablePath() (string, error) {
+	return p.exe, nil
+}
+
+func (p *fakeProcess) Layout() Layout {
+	if p.layout.PointerSize == 0 {
+		return Layout32
+	}
+
+	return p.layout
+}
+
+func (p *fakeProcess) Alive() bool {
+	return !p.exited
+}
+
+func (p *fakeProcess) Maps() ([]Map, error) {
+	if p.exited {
+		return nil, ErrProcessExited
+	}
+
+	maps := make([]Map, len(p.regions))
+	for i, r := range p.regions {
+		maps[i] = r.mapInfo
+	}
+
+	return maps, nil
+}
+
+func (p *fakeProcess) ReadAt(b []byte, off int64) (n int, err error) {
+	p.mu.Lock()
+	p.reads++
+	p.mu.Unlock()
+
+	if p.exited {
+		return 0, fmt.Errorf("reading 0x%x: %w", off, ErrProcessExited)
+	}
+
+	if err, ok := p.fail[off]; ok {
+		return 0, err
+	}
+
+	for _, r := range p.regions {
+		if n == len(b) {
+			break
+		}
+
+		addr := off + int64(n)
+		if addr < r.start || addr >= r.start+r.size {
+			continue
+		}
+
+		n += copy(b[n:], r.data[addr-r.start:])
+	}
+
+	if n < len(b) {
+		return n, fmt.Errorf("reading 0x%x: %w", off+int64(n), ErrUnmapped)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,356 @@
+package memory
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Windows minidump (.dmp) files as written by MiniDumpWriteDump, Task Manager
+// or procdump. Only the streams needed to read memory are parsed.
+const (
+	minidumpSignature = 0x504d444d // "MDMP"
+
+	moduleListStream     = 4
+	memoryListStream     = 5
+	memory64ListStream   = 9
+	miscInfoStream       = 15
+	memoryInfoListStream = 16
+
+	miscProcessID = 0x1
+)
+
+var (
+	ErrNotMinidump = errors.New("not a minidump file")
+	ErrNotDumped   = fmt.Errorf("%w, it is not contained in the minidump", ErrUnmapped)
+)
+
+type minidumpHeader struct {
+	Signature          uint32
+	Version            uint32
+	NumberOfStreams    uint32
+	StreamDirectoryRva uint32
+	CheckSum           uint32
+	TimeDateStamp      uint32
+	Flags              uint64
+}
+
+type minidumpDirectory struct {
+	StreamType uint32
+	DataSize   uint32
+	Rva        uint32
+}
+
+type minidumpModule struct {
+	BaseOfImage   uint64
+	SizeOfImage   uint32
+	CheckSum      uint32
+	TimeDateStamp uint32
+	ModuleNameRva uint32
+	VersionInfo   [13]uint32
+	CvRecord      [2]uint32
+	MiscRecord    [2]uint32
+	Reserved0     uint64
+	Reserved1     uint64
+}
+
+type minidumpMemoryInfo struct {
+	BaseAddress       uint64
+	AllocationBase    uint64
+	AllocationProtect uint32
+	_                 uint32
+	RegionSize        uint64
+	State             uint32
+	Protect           uint32
+	Type              uint32
+	_                 uint32
+}
+
+type minidumpModuleInfo struct {
+	name string
+	base int64
+	size int64
+}
+
+// Minidump is a Process backed by a Windows minidump file.
+type Minidump struct {
+	f       *os.File
+	pid     int
+	chunks  []fileChunk
+	info    []minidumpMemoryInfo
+	modules []minidumpModuleInfo
+}
+
+// OpenMinidump opens a minidump file. Full memory dumps (Memory64ListStream)
+// and the smaller MemoryListStream dumps are supported.
+func OpenMinidump(path string) (*Minidump, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Minidump{f: f}
+	if err := d.parse(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+func (d *Minidump) readStruct(off int64, data interface{}) error {
+	r := io.NewSectionReader(d.f, off, int64(binary.Size(data)))
+	return binary.Read(r, binary.LittleEndian, data)
+}
+
+func (d *Minidump) parse() error {
+	var hdr minidumpHeader
+	if err := d.readStruct(0, &hdr); err != nil || hdr.Signature != minidumpSignature {
+		return ErrNotMinidump
+	}
+
+	dirs := make([]minidumpDirectory, hdr.NumberOfStreams)
+	if err := d.readStruct(int64(hdr.StreamDirectoryRva), dirs); err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		var err error
+
+		switch dir.StreamType {
+		case moduleListStream:
+			err = d.parseModules(int64(dir.Rva))
+		case memoryListStream:
+			err = d.parseMemory(int64(dir.Rva))
+		case memory64ListStream:
+			err = d.parseMemory64(int64(dir.Rva))
+		case memoryInfoListStream:
+			err = d.parseMemoryInfo(int64(dir.Rva))
+		case miscInfoStream:
+			err = d.parseMiscInfo(int64(dir.Rva))
+		}
+
+		if err != nil {
+			return fmt.Errorf("stream %d: %w", dir.StreamType, err)
+		}
+	}
+
+	sort.Slice(d.chunks, func(i, j int) bool {
+		return d.chunks[i].addr < d.chunks[j].addr
+	})
+
+	sort.Slice(d.info, func(i, j int) bool {
+		return d.info[i].BaseAddress < d.info[j].BaseAddress
+	})
+
+	return nil
+}
+
+func (d *Minidump) parseModules(rva int64) error {
+	var count uint32
+	if err := d.readStruct(rva, &count); err != nil {
+		return err
+	}
+
+	modules := make([]minidumpModule, count)
+	if err := d.readStruct(rva+4, modules); err != nil {
+		return err
+	}
+
+	for _, mod := range modules {
+		name, err := d.readString(int64(mod.ModuleNameRva))
+		if err != nil {
+			return err
+		}
+
+		d.modules = append(d.modules, minidumpModuleInfo{
+			name: name,
+			base: int64(mod.BaseOfImage),
+			size: int64(mod.SizeOfImage),
+		})
+	}
+
+	return nil
+}
+
+func (d *Minidump) parseMemory(rva int64) error {
+	var count uint32
+	if err := d.readStruct(rva, &count); err != nil {
+		return err
+	}
+
+	// MINIDUMP_MEMORY_DESCRIPTOR: start, size, rva
+	descs := make([]struct {
+		Start uint64
+		Size  uint32
+		Rva   uint32
+	}, count)
+	if err := d.readStruct(rva+4, descs); err != nil {
+		return err
+	}
+
+	for _, desc := range descs {
+		d.chunks = append(d.chunks, fileChunk{int64(desc.Start), int64(desc.Size), int64(desc.Rva)})
+	}
+
+	return nil
+}
+
+func (d *Minidump) parseMemory64(rva int64) error {
+	var hdr struct {
+		Count   uint64
+		BaseRva uint64
+	}
+	if err := d.readStruct(rva, &hdr); err != nil {
+		return err
+	}
+
+	// MINIDUMP_MEMORY_DESCRIPTOR64: start, size. The data of all ranges is
+	// stored back to back starting at BaseRva.
+	descs := make([][2]uint64, hdr.Count)
+	if err := d.readStruct(rva+16, descs); err != nil {
+		return err
+	}
+
+	fileOff := int64(hdr.BaseRva)
+	for _, desc := range descs {
+		d.chunks = append(d.chunks, fileChunk{int64(desc[0]), int64(desc[1]), fileOff})
+		fileOff += int64(desc[1])
+	}
+
+	return nil
+}
+
+func (d *Minidump) parseMemoryInfo(rva int64) error {
+	var hdr struct {
+		SizeOfHeader    uint32
+		SizeOfEntry     uint32
+		NumberOfEntries uint64
+	}
+	if err := d.readStruct(rva, &hdr); err != nil {
+		return err
+	}
+
+	if hdr.SizeOfEntry < uint32(binary.Size(minidumpMemoryInfo{})) {
+		return fmt.Errorf("memory info entry too small (%d bytes)", hdr.SizeOfEntry)
+	}
+
+	for i := uint64(0); i < hdr.NumberOfEntries; i++ {
+		var info minidumpMemoryInfo
+		off := rva + int64(hdr.SizeOfHeader) + int64(i)*int64(hdr.SizeOfEntry)
+		if err := d.readStruct(off, &info); err != nil {
+			return err
+		}
+		d.info = append(d.info, info)
+	}
+
+	return nil
+}
+
+func (d *Minidump) parseMiscInfo(rva int64) error {
+	var info struct {
+		SizeOfInfo uint32
+		Flags1     uint32
+		ProcessID  uint32
+	}
+	if err := d.readStruct(rva, &info); err != nil {
+		return err
+	}
+
+	if info.Flags1&miscProcessID != 0 {
+		d.pid = int(info.ProcessID)
+	}
+
+	return nil
+}
+
+// readString reads a MINIDUMP_STRING, a byte length followed by UTF-16.
+func (d *Minidump) readString(rva int64) (string, error) {
+	var length uint32
+	if err := d.readStruct(rva, &length); err != nil {
+		return "", err
+	}
+
+	buf16 := make([]uint16, length/2)
+	if err := d.readStruct(rva+4, buf16); err != nil {
+		return "", err
+	}
+
+	return string(utf16.Decode(buf16)), nil
+}
+
+// memoryInfo returns the MemoryInfoListStream entry containing addr.
+func (d *Minidump) memoryInfo(addr int64) (minidumpMemoryInfo, bool) {
+	i := sort.Search(len(d.info), func(i int) bool {
+		return int64(d.info[i].BaseAddress+d.info[i].RegionSize) > addr
+	})
+
+	if i < len(d.info) && int64(d.info[i].BaseAddress) <= addr {
+		return d.info[i], true
+	}
+
+	return minidumpMemoryInfo{}, false
+}
+
+// ExecutablePath returns the path of the first .exe module in the dump.
+func (d *Minidump) ExecutablePath() (string, error) {
+	for _, mod := range d.modules {
+		if strings.HasSuffix(strings.ToLower(mod.name), ".exe") {
+			return mod.name, nil
+		}
+	}
+
+	if len(d.modules) > 0 {
+		return d.modules[0].name, nil
+	}
+
+	return "", errors.New("minidump has no module list")
+}
+
+func (d *Minidump) Close() error {
+	return d.f.Close()
+}
+
+func (d *Minidump) Pid() int {
+	return d.pid
+}
+
+func (d *Minidump) ReadAt(b []byte, off int64) (n int, err error) {
+	return readFileChunks(d.f, d.chunks, b, off, ErrNotDumped)
+}
+
+func (d *Minidump) Maps() ([]Map, error) {
+	maps := make([]Map, 0, len(d.chunks))
+
+	for _, c := range d.chunks {
+		m := mapInfo{start: c.addr, size: c.size, prot: ProtRead, module: d.module(c.addr)}
+
+		if info, ok := d.memoryInfo(c.addr); ok {
+			m.prot = windowsProtection(info.Protect)
+			m.state = windowsState(info.State)
+			m.type_ = windowsType(info.Type)
+		} else if m.module != "" {
+			m.type_ = TypeImage
+		}
+
+		maps = append(maps, m)
+	}
+
+	return maps, nil
+}
+
+// module returns the path of the module loaded at addr.
+func (d *Minidump) module(addr int64) string {
+	for _, mod := range d.modules {
+		if addr >= mod.base && addr < mod.base+mod.size {
+			return mod.name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// dumpWriter lays out a minidump file. Data is appended in the order it is
+// put, streams are listed in the directory written by save.
+type dumpWriter struct {
+	bytes.Buffer
+	dirs []minidumpDirectory
+}
+
+func newDumpWriter() *dumpWriter {
+	w := &dumpWriter{}
+	w.Write(make([]byte, binary.Size(minidumpHeader{})))
+	return w
+}
+
+// put appends data and returns its rva.
+func (w *dumpWriter) put(data ...interface{}) uint32 {
+	rva := uint32(w.Len())
+	for _, d := range data {
+		_ = binary.Write(w, binary.LittleEndian, d)
+	}
+
+	return rva
+}
+
+func (w *dumpWriter) putString(s string) uint32 {
+	s16 := utf16.Encode([]rune(s))
+	return w.put(uint32(2*len(s16)), s16)
+}
+
+func (w *dumpWriter) stream(streamType uint32, data ...interface{}) {
+	rva := w.put(data...)
+	w.dirs = append(w.dirs, minidumpDirectory{streamType, uint32(w.Len()) - rva, rva})
+}
+
+func (w *dumpWriter) save(t *testing.T) string {
+	t.Helper()
+
+	dirRva := w.put(w.dirs)
+
+	var hdr bytes.Buffer
+	_ = binary.Write(&hdr, binary.LittleEndian, minidumpHeader{
+		Signature:          minidumpSignature,
+		NumberOfStreams:    uint32(len(w.dirs)),
+		StreamDirectoryRva: dirRva,
+	})
+
+	b := w.Bytes()
+	copy(b, hdr.Bytes())
+
+	path := filepath.Join(t.TempDir(), "test.dmp")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestMinidumpFull(t *testing.T) {
+	w := newDumpWriter()
+
+	exe := w.putString(`C:\osu!\osu!.exe`)
+	dll := w.putString(`C:\windows\system32\ntdll.dll`)
+	w.stream(moduleListStream, uint32(2),
+		minidumpModule{BaseOfImage: 0x7f000000, SizeOfImage: 0x1000, ModuleNameRva: dll},
+		minidumpModule{BaseOfImage: 0x400000, SizeOfImage: 0x1000, ModuleNameRva: exe})
+
+	data := w.put([]byte("MZ..image..data."), []byte("heapdata"))
+	w.stream(memory64ListStream, uint64(2), uint64(data),
+		[2]uint64{0x400000, 16}, [2]uint64{0x500000, 8})
+
+	w.stream(memoryInfoListStream, uint32(16), uint32(binary.Size(minidumpMemoryInfo{})), uint64(2),
+		minidumpMemoryInfo{BaseAddress: 0x500000, RegionSize: 0x1000,
+			State: memCommit, Protect: pageReadWrite, Type: memPrivate},
+		minidumpMemoryInfo{BaseAddress: 0x400000, RegionSize: 0x1000,
+			State: memCommit, Protect: pageExecuteRead, Type: memImage})
+
+	w.stream(miscInfoStream, uint32(24), uint32(miscProcessID), uint32(4321), [3]uint32{})
+
+	d, err := OpenMinidump(w.save(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Pid() != 4321 {
+		t.Errorf("minidump pid %d, want 4321", d.Pid())
+	}
+
+	if exe, err := d.ExecutablePath(); err != nil || exe != `C:\osu!\osu!.exe` {
+		t.Errorf("minidump ExecutablePath = %q, %v", exe, err)
+	}
+
+	maps, err := d.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMaps := []Map{
+		mapInfo{start: 0x400000, size: 16, prot: ProtRead | ProtExec, type_: TypeImage, module: `C:\osu!\osu!.exe`},
+		mapInfo{start: 0x500000, size: 8, prot: ProtRead | ProtWrite, type_: TypePrivate},
+	}
+	if !reflect.DeepEqual(maps, wantMaps) {
+		t.Errorf("minidump maps %+v, want %+v", maps, wantMaps)
+	}
+
+	b := make([]byte, 8)
+	if n, err := d.ReadAt(b, 0x400008); err != nil || string(b[:n]) != "e..data." {
+		t.Errorf("ReadAt(0x400008) = %q, %v", b[:n], err)
+	}
+
+	if n, err := d.ReadAt(b, 0x500000); err != nil || string(b[:n]) != "heapdata" {
+		t.Errorf("ReadAt(0x500000) = %q, %v", b[:n], err)
+	}
+
+	if n, err := d.ReadAt(b, 0x500004); n != 4 || !errors.Is(err, ErrNotDumped) || !errors.Is(err, ErrUnmapped) {
+		t.Errorf("ReadAt(0x500004) = %q, %v, want 4 bytes and ErrNotDumped", b[:n], err)
+	}
+}
+
+func TestMinidumpMemoryList(t *testing.T) {
+	w := newDumpWriter()
+
+	dll := w.putString(`C:\windows\system32\ntdll.dll`)
+	w.stream(moduleListStream, uint32(1),
+		minidumpModule{BaseOfImage: 0x7f000000, SizeOfImage: 0x1000, ModuleNameRva: dll})
+
+	type memoryDescriptor struct {
+		Start uint64
+		Size  uint32
+		Rva   uint32
+	}
+
+	stack := w.put([]byte("stack!"))
+	code := w.put([]byte("code"))
+	w.stream(memoryListStream, uint32(2),
+		memoryDescriptor{0x7f000100, 4, code},
+		memoryDescriptor{0x100000, 6, stack})
+
+	d, err := OpenMinidump(w.save(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if exe, err := d.ExecutablePath(); err != nil || exe != `C:\windows\system32\ntdll.dll` {
+		t.Errorf("minidump ExecutablePath = %q, %v", exe, err)
+	}
+
+	maps, err := d.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMaps := []Map{
+		mapInfo{start: 0x100000, size: 6, prot: ProtRead},
+		mapInfo{start: 0x7f000100, size: 4, prot: ProtRead, type_: TypeImage, module: `C:\windows\system32\ntdll.dll`},
+	}
+	if !reflect.DeepEqual(maps, wantMaps) {
+		t.Errorf("minidump maps %+v, want %+v", maps, wantMaps)
+	}
+
+	b := make([]byte, 4)
+	if n, err := d.ReadAt(b, 0x7f000100); err != nil || string(b[:n]) != "code" {
+		t.Errorf("ReadAt(0x7f000100) = %q, %v", b[:n], err)
+	}
+}
+
+func TestOpenMinidumpErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.dmp")
+	if err := os.WriteFile(path, []byte("BOSUSNAP"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenMinidump(path); !errors.Is(err, ErrNotMinidump) {
+		t.Errorf("OpenMinidump(snapshot) = %v, want ErrNotMinidump", err)
+	}
+}
@@ -0,0 +1,462 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const progressInterval = 250 * time.Millisecond
+
+// multiScanner finds several patterns in a single pass over memory. The longest
+// run of fixed bytes of every pattern is fed into an Aho-Corasick automaton,
+// every hit of such an anchor is then verified against the full pattern and
+// its mask.
+type multiScanner struct {
+	pats   []pattern
+	maxLen int
+
+	// anchors holds the index after the last anchor byte of every pattern
+	anchors []int
+
+	delta   [][256]int32
+	outputs [][]int
+}
+
+func newMultiScanner(pats []pattern) (*multiScanner, error) {
+	s := &multiScanner{
+		pats:    pats,
+		anchors: make([]int, len(pats)),
+		delta:   make([][256]int32, 1),
+		outputs: make([][]int, 1),
+	}
+
+	for i, pat := range pats {
+		start, end := pat.anchor()
+		if start == end {
+			return nil, fmt.Errorf("%w %q: only wildcards", ErrInvalidPattern, pat)
+		}
+
+		s.anchors[i] = end
+		if len(pat.bytes) > s.maxLen {
+			s.maxLen = len(pat.bytes)
+		}
+
+		if pat.mask[start] == 0xFF {
+			s.insert(pat.bytes[start:end], i)
+			continue
+		}
+
+		// a partly masked anchor byte is inserted with every value it
+		// can have
+		for b := 0; b < 256; b++ {
+			if byte(b)&pat.mask[start] == pat.bytes[start] {
+				s.insert([]byte{byte(b)}, i)
+			}
+		}
+	}
+
+	s.build()
+
+	return s, nil
+}
+
+// insert adds an anchor to the trie. Missing transitions are 0 until build
+// turns the trie into a full automaton.
+func (s *multiScanner) insert(anchor []byte, pat int) {
+	state := int32(0)
+
+	for _, b := range anchor {
+		if s.delta[state][b] == 0 {
+			s.delta = append(s.delta, [256]int32{})
+			s.outputs = append(s.outputs, nil)
+			s.delta[state][b] = int32(len(s.delta) - 1)
+		}
+		state = s.delta[state][b]
+	}
+
+	s.outputs[state] = append(s.outputs[state], pat)
+}
+
+// build computes the failure links breadth first and folds them into delta,
+// so scanning needs exactly one table lookup per byte.
+func (s *multiScanner) build() {
+	fail := make([]int32, len(s.delta))
+	queue := make([]int32, 0, len(s.delta))
+
+	for b := 0; b < 256; b++ {
+		if next := s.delta[0][b]; next != 0 {
+			queue = append(queue, next)
+		}
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		s.outputs[state] = append(s.outputs[state], s.outputs[fail[state]]...)
+
+		for b := 0; b < 256; b++ {
+			next := s.delta[state][b]
+			if next == 0 {
+				s.delta[state][b] = s.delta[fail[state]][b]
+				continue
+			}
+
+			fail[next] = s.delta[fail[state]][b]
+			queue = append(queue, next)
+		}
+	}
+}
+
+// search reports the start of every verified match in buf whose start is
+// before limit. The whole pattern has to fit into buf.
+func (s *multiScanner) search(buf []byte, limit int, found func(pat, at int)) {
+	state := int32(0)
+
+	for i, b := range buf {
+		state = s.delta[state][b]
+
+		for _, pat := range s.outputs[state] {
+			p := s.pats[pat]
+
+			begin := i + 1 - s.anchors[pat]
+			if begin < 0 || begin >= limit || begin+len(p.bytes) > len(buf) {
+				continue
+			}
+
+			if p.match(buf[begin:]) {
+				found(pat, begin)
+			}
+		}
+	}
+}
+
+// scanRegion reads reg in chunks that overlap by the length of the longest
+// pattern and calls found for every match. It stops early once found
+// returns false or ctx is done.
+func (s *multiScanner) scanRegion(ctx context.Context, p Process, reg Map, buf []byte,
+	scanned func(n int64), found func(pat int, addr int64) bool) error {
+	overlap := int64(s.maxLen - 1)
+
+	for i := int64(0); i < reg.Size(); {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		nToRead := reg.Size() - i
+		if nToRead > int64(len(buf)) {
+			nToRead = int64(len(buf))
+		}
+
+		n, err := p.ReadAt(buf[:nToRead], reg.Start()+i)
+		if n <= 0 {
+			return err
+		}
+
+		next := int64(n) - overlap
+		if next <= 0 || i+int64(n) >= reg.Size() {
+			next = int64(n)
+		}
+
+		done := false
+		s.search(buf[:n], int(next), func(pat, at int) {
+			if !done && !found(pat, reg.Start()+i+int64(at)) {
+				done = true
+			}
+		})
+
+		scanned(next)
+
+		if done || err != nil {
+			return err
+		}
+
+		i += next
+	}
+
+	return nil
+}
+
+// scanState collects the results of the workers of a scan. For every pattern
+// the match in the region with the lowest index wins, so the result is the
+// same as scanning the regions one after another. With all set every match
+// is counted, and kept unless countOnly is set, and the scan never ends
+// early.
+type scanState struct {
+	mu sync.Mutex
+
+	addrs  []int64
+	ok     []bool
+	region []int
+	err    error
+
+	all       bool
+	countOnly bool
+	matches   [][]Match
+	counts    []int
+
+	progress ScanProgress
+	reporter progressReporter
+}
+
+// found records a match in region j and reports whether region j can still
+// improve the result of any pattern.
+func (st *scanState) found(j int, reg Map, pat int, addr int64) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if !st.ok[pat] || j < st.region[pat] {
+		if !st.ok[pat] {
+			st.progress.Found++
+		}
+		st.addrs[pat], st.ok[pat], st.region[pat] = addr, true, j
+	}
+
+	if st.all {
+		st.counts[pat]++
+		if !st.countOnly {
+			st.matches[pat] = append(st.matches[pat], Match{Address: addr, Region: reg})
+		}
+		return true
+	}
+
+	for i := range st.ok {
+		if !st.ok[i] || st.region[i] > j {
+			return true
+		}
+	}
+
+	return false
+}
+
+// complete reports whether no region from j on can change the result.
+func (st *scanState) complete(j int) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.all {
+		return false
+	}
+
+	for i := range st.ok {
+		if !st.ok[i] || st.region[i] >= j {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (st *scanState) fail(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.err == nil {
+		st.err = err
+	}
+}
+
+func (st *scanState) scanned(n int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.progress.BytesScanned += n
+	st.reportProgress(false)
+}
+
+func (st *scanState) regionDone() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.progress.RegionsDone++
+	st.reportProgress(false)
+}
+
+// reportProgress reports the progress of the scan, st.mu has to be held.
+func (st *scanState) reportProgress(force bool) {
+	st.reporter.report(st.progress, force)
+}
+
+// progressReporter calls a progress callback at most every
+// progressInterval unless forced. A nil callback is never called.
+type progressReporter struct {
+	callback func(ScanProgress)
+	reported time.Time
+}
+
+func (r *progressReporter) report(progress ScanProgress, force bool) {
+	if r.callback == nil || (!force && time.Since(r.reported) < progressInterval) {
+		return
+	}
+
+	r.reported = time.Now()
+	r.callback(progress)
+}
+
+// scan returns the first match of every pattern, ok is false for patterns
+// that were not found.
+func (s *multiScanner) scan(ctx context.Context, p Process, opt *ScanOptions) (addrs []int64, ok []bool, err error) {
+	st, err := s.run(ctx, p, opt, scanFirst)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return st.addrs, st.ok, nil
+}
+
+// scanAll returns every match of every pattern sorted by address.
+func (s *multiScanner) scanAll(ctx context.Context, p Process, opt *ScanOptions) ([][]Match, error) {
+	st, err := s.run(ctx, p, opt, scanEvery)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, matches := range st.matches {
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].Address < matches[j].Address
+		})
+	}
+
+	return st.matches, nil
+}
+
+// count returns the number of matches of every pattern without keeping
+// them, patterns matching all over memory cost nothing.
+func (s *multiScanner) count(ctx context.Context, p Process, opt *ScanOptions) ([]int, error) {
+	st, err := s.run(ctx, p, opt, scanCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.counts, nil
+}
+
+// scanMode is what run looks for.
+type scanMode uint8
+
+const (
+	scanFirst scanMode = iota
+	scanEvery
+	scanCount
+)
+
+// run scans the regions selected by opt, distributed over opt.Workers
+// goroutines.
+func (s *multiScanner) run(ctx context.Context, p Process, opt *ScanOptions, mode scanMode) (*scanState, error) {
+	regs, err := p.Maps()
+	if err != nil {
+		return nil, err
+	}
+
+	var maps []Map
+	for _, reg := range regs {
+		if Readable(reg) && (opt.Filter == nil || opt.Filter(reg)) {
+			maps = append(maps, reg)
+		}
+	}
+
+	st := &scanState{
+		addrs:     make([]int64, len(s.pats)),
+		ok:        make([]bool, len(s.pats)),
+		region:    make([]int, len(s.pats)),
+		all:       mode != scanFirst,
+		countOnly: mode == scanCount,
+		matches:   make([][]Match, len(s.pats)),
+		counts:    make([]int, len(s.pats)),
+		reporter:  progressReporter{callback: opt.Progress},
+	}
+
+	st.progress.RegionsTotal = len(maps)
+	st.progress.Total = len(s.pats)
+	for _, reg := range maps {
+		st.progress.BytesTotal += reg.Size()
+	}
+
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	const bufSize = 65536
+
+	scanOne := func(j int, buf []byte) {
+		err := s.scanRegion(scanCtx, p, maps[j], buf, st.scanned, func(pat int, addr int64) bool {
+			return st.found(j, maps[j], pat, addr)
+		})
+
+		if err != nil && !isAlive(p) {
+			st.fail(ErrProcessExited)
+			cancel()
+		}
+
+		st.regionDone()
+	}
+
+	if workers == 1 {
+		// region j is only read if region j-1 didn't complete the scan, so
+		// the reads are the same every time and traces replay
+		buf := make([]byte, bufSize+s.maxLen)
+
+		for j := range maps {
+			if st.complete(j) || scanCtx.Err() != nil {
+				break
+			}
+
+			scanOne(j, buf)
+		}
+	} else {
+		regions := make(chan int)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				buf := make([]byte, bufSize+s.maxLen)
+				for j := range regions {
+					scanOne(j, buf)
+				}
+			}()
+		}
+
+	feed:
+		for j := range maps {
+			if st.complete(j) {
+				break
+			}
+
+			select {
+			case regions <- j:
+			case <-scanCtx.Done():
+				break feed
+			}
+		}
+
+		close(regions)
+		wg.Wait()
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.reportProgress(true)
+
+	if st.err != nil {
+		return nil, st.err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
@@ -0,0 +1,361 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scanAlphabet are the bytes the memory and the patterns of the randomized
+// scans are made of, few enough that short patterns match all over.
+var scanAlphabet = []string{"12", "34", "1F", "3F"}
+
+// randomPattern returns a pattern of n bytes of scanAlphabet, some of them
+// masked partly or completely.
+func randomPattern(rnd *rand.Rand, n int) string {
+	tokens := make([]string, n)
+	fixed := false
+
+	for i := range tokens {
+		b := scanAlphabet[rnd.Intn(len(scanAlphabet))]
+
+		switch rnd.Intn(6) {
+		case 0:
+			b = "??"
+		case 1:
+			b = "?" + b[1:]
+		case 2:
+			b = b[:1] + "?"
+		default:
+			fixed = true
+		}
+
+		tokens[i] = b
+	}
+
+	if !fixed {
+		tokens[0] = scanAlphabet[0]
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// newScanProcess maps regions of random scanAlphabet bytes. The first one
+// is several scan chunks long.
+func newScanProcess(rnd *rand.Rand) *fakeProcess {
+	p := newFakeProcess(Layout{})
+
+	for _, reg := range []struct{ start, size int64 }{
+		{0x100000, 0x30000},
+		{0x200000, 0x1000},
+		{0x210000, 0x11000},
+		{0x300000, 0x10},
+	} {
+		data := make([]byte, reg.size)
+		for i := range data {
+			data[i] = []byte{0x12, 0x34, 0x1F, 0x3F}[rnd.Intn(4)]
+		}
+
+		p.mapRegion(reg.start, reg.size)
+		p.write(reg.start, data)
+	}
+
+	return p
+}
+
+// naiveScan matches pat at every address of every region of p that is
+// selected by filter, in order.
+func naiveScan(p *fakeProcess, pat pattern, filter Filter) []int64 {
+	var addrs []int64
+
+	for _, r := range p.regions {
+		if filter != nil && !filter(r.mapInfo) {
+			continue
+		}
+
+		for i := 0; i+len(pat.bytes) <= len(r.data); i++ {
+			if pat.match(r.data[i:]) {
+				addrs = append(addrs, r.start+int64(i))
+			}
+		}
+	}
+
+	return addrs
+}
+
+func TestMultiScanner(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	p := newScanProcess(rnd)
+
+	// a long pattern straddling the chunk boundaries of the first region
+	// and one at the end of the region behind it
+	long := make([]string, 24)
+	for i := range long {
+		long[i] = scanAlphabet[rnd.Intn(len(scanAlphabet))]
+	}
+
+	var longBytes []byte
+	for _, b := range long {
+		pat, _ := parsePattern(b)
+		longBytes = append(longBytes, pat.bytes[0])
+	}
+
+	p.write(0x100000+0x10000-10, longBytes)
+	p.write(0x100000+0x20000-23, longBytes)
+	p.write(0x200000+0x1000-24, longBytes)
+
+	signatures := []string{
+		strings.Join(long, " "),
+		// every anchor byte is masked partly
+		"?F ?? 3?",
+		"1? ?4",
+		// the same anchor as another pattern
+		"12 34 ?? 1F",
+		"12 34 ?? 3F",
+		// longer than the smallest region
+		"12 34 1F 3F 12 34 1F 3F 12 34 1F 3F 12 34 1F 3F 12",
+	}
+	for i := 0; i < 40; i++ {
+		signatures = append(signatures, randomPattern(rnd, 2+rnd.Intn(8)))
+	}
+
+	pats := make([]pattern, len(signatures))
+	for i, sig := range signatures {
+		pat, err := parsePattern(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pats[i] = pat
+	}
+
+	s, err := newMultiScanner(pats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := []struct {
+		name   string
+		filter Filter
+	}{
+		{"all", nil},
+		{"behind the first", func(m Map) bool { return m.Start() != 0x100000 }},
+	}
+
+	for _, workers := range []int{1, 4} {
+		for _, f := range filters {
+			opt := &ScanOptions{Workers: workers, Filter: f.filter}
+
+			all, err := s.scanAll(context.Background(), p, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counts, err := s.count(context.Background(), p, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			addrs, ok, err := s.scan(context.Background(), p, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, pat := range pats {
+				want := naiveScan(p, pat, f.filter)
+
+				if !equalMatches(all[i], want) {
+					t.Errorf("%d workers, %s: scanAll(%q) found %d matches, want %d",
+						workers, f.name, pat, len(all[i]), len(want))
+				}
+
+				if counts[i] != len(want) {
+					t.Errorf("%d workers, %s: count(%q) = %d, want %d", workers, f.name, pat, counts[i], len(want))
+				}
+
+				// the first match of the lowest region wins
+				if ok[i] != (len(want) > 0) || ok[i] && addrs[i] != want[0] {
+					t.Errorf("%d workers, %s: scan(%q) = 0x%x, %v, want the first of %d matches",
+						workers, f.name, pat, addrs[i], ok[i], len(want))
+				}
+			}
+		}
+	}
+}
+
+func equalMatches(matches []Match, addrs []int64) bool {
+	if len(matches) != len(addrs) {
+		return false
+	}
+
+	for i, m := range matches {
+		if m.Address != addrs[i] || m.Region == nil ||
+			m.Address < m.Region.Start() || m.Address >= m.Region.Start()+m.Region.Size() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMultiScannerStopsEarly(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x10000, 0x1000)
+	p.mapRegion(0x20000, 0x1000)
+	p.mapRegion(0x30000, 0x1000)
+	p.write(0x10800, []byte{0xAB, 0xCD})
+	p.write(0x20000, []byte{0xAB, 0xCD, 0xEF})
+
+	pats := []pattern{}
+	for _, sig := range []string{"AB CD", "AB CD EF"} {
+		pat, _ := parsePattern(sig)
+		pats = append(pats, pat)
+	}
+
+	s, err := newMultiScanner(pats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addrs, ok, err := s.scan(context.Background(), p, &ScanOptions{Workers: 1})
+	if err != nil || !ok[0] || !ok[1] || addrs[0] != 0x10800 || addrs[1] != 0x20000 {
+		t.Fatalf("scan = %x, %v, %v", addrs, ok, err)
+	}
+
+	// the third region can't change the result and isn't read
+	if p.reads != 2 {
+		t.Errorf("%d reads, want 2", p.reads)
+	}
+}
+
+// cancellingProcess cancels a scan once it read a number of chunks and
+// counts the reads still running.
+type cancellingProcess struct {
+	*fakeProcess
+
+	mu      sync.Mutex
+	left    int
+	active  int
+	cancel  context.CancelFunc
+	stopped bool
+	late    int
+}
+
+func (p *cancellingProcess) ReadAt(b []byte, off int64) (int, error) {
+	p.mu.Lock()
+	if p.stopped {
+		p.late++
+	}
+	p.active++
+	if p.left--; p.left == 0 {
+		p.cancel()
+	}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+
+	return p.fakeProcess.ReadAt(b, off)
+}
+
+func TestMultiScannerCancel(t *testing.T) {
+	fake := newFakeProcess(Layout{})
+	for i := int64(0); i < 64; i++ {
+		fake.mapRegion(0x100000+i*0x20000, 0x20000)
+	}
+
+	pat, _ := parsePattern("AB CD")
+	s, err := newMultiScanner([]pattern{pat})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := &cancellingProcess{fakeProcess: fake, left: 10, cancel: cancel}
+
+		var final ScanProgress
+		_, err := s.scanAll(ctx, p, &ScanOptions{Workers: workers, Progress: func(sp ScanProgress) { final = sp }})
+
+		p.mu.Lock()
+		p.stopped = true
+		active := p.active
+		p.mu.Unlock()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%d workers: scanAll = %v, want context.Canceled", workers, err)
+		}
+
+		// every worker returned before the scan did
+		if active != 0 {
+			t.Errorf("%d workers: %d reads still running", workers, active)
+		}
+
+		if final.RegionsDone >= final.RegionsTotal || final.BytesScanned >= final.BytesTotal {
+			t.Errorf("%d workers: cancelled scan reported %+v", workers, final)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if p.late != 0 {
+			t.Errorf("%d workers: %d reads after the scan returned", workers, p.late)
+		}
+
+		cancel()
+	}
+}
+
+func TestMultiScannerProgress(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x100000, 0x30000)
+	p.mapRegion(0x200000, 0x1000)
+	// not readable, left out of the totals
+	p.mapRegion(0x300000, 0x1000).prot = 0
+	p.mapRegion(0x400000, 0x1000).state = StateReserved
+	p.write(0x100000+0x10000-1, []byte{0xAB, 0xCD})
+	p.write(0x200ffe, []byte{0xAB, 0xCD})
+
+	var pats []pattern
+	for _, sig := range []string{"AB CD", "AB CD EF"} {
+		pat, _ := parsePattern(sig)
+		pats = append(pats, pat)
+	}
+
+	s, err := newMultiScanner(pats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		calls := 0
+		var final ScanProgress
+		opt := &ScanOptions{Workers: workers, Progress: func(sp ScanProgress) {
+			calls++
+			final = sp
+		}}
+
+		if _, err := s.scanAll(context.Background(), p, opt); err != nil {
+			t.Fatal(err)
+		}
+
+		want := ScanProgress{
+			BytesScanned: 0x31000,
+			BytesTotal:   0x31000,
+			RegionsDone:  2,
+			RegionsTotal: 2,
+			Found:        1,
+			Total:        2,
+		}
+
+		// the first report comes right away, the scan ends before the
+		// next one is due and only the forced report has the totals
+		if final != want || calls > 2 {
+			t.Errorf("%d workers: %d reports, the last %+v, want %+v", workers, calls, final, want)
+		}
+	}
+}
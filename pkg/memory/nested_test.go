@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type nestedItem struct {
+	ID   int32  `memory:"Self + 0x4"`
+	Name string `memory:"[Self + 0x8]"`
+}
+
+type nestedData struct {
+	Score struct {
+		Combo  int16  `memory:"Self + 0x4"`
+		Player string `memory:"[Self + 0x8]"`
+	} `memory:"[Root + 0x18]"`
+	Dead struct {
+		Value int32 `memory:"Self + 0x4"`
+	} `memory:"[Root + 0x10],optional"`
+	Items []nestedItem `memory:"[Root + 0x1C]"`
+	Array []nestedItem `memory:"[Root + 0x20],array"`
+}
+
+func TestNestedStructs(t *testing.T) {
+	p, addrs := newPlanProcess()
+
+	p.putPtr(0x1118, 0x1300)
+	p.write(0x1304, []byte{150, 0})
+	p.putPtr(0x1308, 0x1400)
+	putString(p, 0x1400, "cookiezi")
+
+	// List<nestedItem> of two, its array has room for four
+	p.putPtr(0x111c, 0x1500)
+	p.putPtr(0x1504, 0x1600)
+	p.putUint32(0x150c, 2)
+	p.putUint32(0x1604, 4)
+	p.putPtr(0x1608, 0x1700)
+	p.putPtr(0x160c, 0x1800)
+
+	p.putUint32(0x1704, 1)
+	p.putPtr(0x1708, 0x1400)
+	p.putUint32(0x1804, 2)
+	p.putPtr(0x1808, 0x9000)
+
+	// nestedItem[] of one
+	p.putPtr(0x1120, 0x1900)
+	p.putUint32(0x1904, 1)
+	p.putPtr(0x1908, 0x1800)
+
+	var data nestedData
+	err := Read(p, addrs, &data)
+
+	if data.Score.Combo != 150 || data.Score.Player != "cookiezi" {
+		t.Errorf("Score = %+v", data.Score)
+	}
+
+	wantItems := []nestedItem{{1, "cookiezi"}, {2, ""}}
+	if !reflect.DeepEqual(data.Items, wantItems) {
+		t.Errorf("Items = %+v, want %+v", data.Items, wantItems)
+	}
+
+	if wantArray := []nestedItem{{2, ""}}; !reflect.DeepEqual(data.Array, wantArray) {
+		t.Errorf("Array = %+v, want %+v", data.Array, wantArray)
+	}
+
+	var rerr ReadError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Read = %v, want a ReadError", err)
+	}
+
+	var required, optional []string
+	for _, ferr := range rerr.Required {
+		required = append(required, ferr.Field)
+	}
+	for _, ferr := range rerr.Optional {
+		optional = append(optional, ferr.Field)
+	}
+
+	if want := []string{"Items[1].Name", "Array[0].Name"}; !reflect.DeepEqual(required, want) {
+		t.Errorf("required fields failed %q, want %q", required, want)
+	}
+
+	// the optional option of a struct applies to its fields
+	if want := []string{"Dead.Value"}; !reflect.DeepEqual(optional, want) {
+		t.Errorf("optional fields failed %q, want %q", optional, want)
+	}
+
+	// elements count their hops from the element
+	if ferr := rerr.Required[0]; ferr.Struct != "nestedData" || ferr.Hop != 1 || ferr.Addr != 0x9000 {
+		t.Errorf("element error %+v", ferr)
+	}
+}
+
+type nestedLoop struct {
+	Next []nestedLoop `memory:"[Self + 0x4]"`
+}
+
+func TestNestedStructErrors(t *testing.T) {
+	if _, err := Compile(&planAddresses{}, &struct {
+		Loop []nestedLoop `memory:"[Root]"`
+	}{}); err == nil {
+		t.Error("Compile of a struct containing itself succeeded")
+	}
+
+	if _, err := Compile(&planAddresses{}, &struct {
+		A int32 `memory:"Self + 0x4"`
+	}{}); err == nil {
+		t.Error("Compile of Self at the top level succeeded")
+	}
+}
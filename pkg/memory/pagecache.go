@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	pageSize = 4096
+
+	// maxCachedRead is the largest read a PageCache caches, bigger reads
+	// go straight to the underlying reader.
+	maxCachedRead = 16 * pageSize
+)
+
+// PageCache is an io.ReaderAt that reads whole pages of another reader and
+// answers every later read from them until Reset is called. Reading a
+// struct with a Plan through a PageCache turns the many small reads of the
+// pointer chains into a few page sized ones.
+//
+// Nothing is ever refreshed by the cache itself, call Reset once per tick
+// before reading so values don't go stale.
+type PageCache struct {
+	r io.ReaderAt
+
+	mu    sync.Mutex
+	pages map[int64][]byte
+	errs  map[int64]error
+
+	// modules are the module bases looked up so far, they survive Reset
+	modules map[string]int64
+}
+
+func NewPageCache(r io.ReaderAt) *PageCache {
+	return &PageCache{
+		r:     r,
+		pages: map[int64][]byte{},
+		errs:  map[int64]error{},
+
+		modules: map[string]int64{},
+	}
+}
+
+// Reset forgets every page read so far, failed reads included.
+func (c *PageCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pages = map[int64][]byte{}
+	c.errs = map[int64]error{}
+}
+
+// Alive forwards to the underlying reader, so read errors can tell dead
+// processes from unmapped addresses.
+func (c *PageCache) Alive() bool {
+	return isAlive(c.r)
+}
+
+// Layout returns the layout of the underlying reader.
+func (c *PageCache) Layout() Layout {
+	return layoutOf(c.r)
+}
+
+// ModuleBase looks up the base address of a module in the maps of the
+// underlying reader once, modules are assumed not to move while the cache
+// is used.
+func (c *PageCache) ModuleBase(name string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if base, ok := c.modules[key]; ok {
+		return base, nil
+	}
+
+	base, err := moduleBase(c.r, name)
+	if err != nil {
+		return 0, err
+	}
+
+	c.modules[key] = base
+	return base, nil
+}
+
+func (c *PageCache) ReadAt(b []byte, off int64) (n int, err error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	if len(b) > maxCachedRead {
+		return c.r.ReadAt(b, off)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fill(pageOf(off), pageOf(off+int64(len(b))-1))
+
+	for n < len(b) {
+		addr := off + int64(n)
+		page := pageOf(addr)
+
+		if err := c.errs[page]; err != nil {
+			return n, err
+		}
+
+		n += copy(b[n:], c.pages[page][addr-page:])
+	}
+
+	return n, nil
+}
+
+func pageOf(addr int64) int64 {
+	return addr &^ (pageSize - 1)
+}
+
+func (c *PageCache) cached(page int64) bool {
+	_, ok := c.pages[page]
+	_, failed := c.errs[page]
+	return ok || failed
+}
+
+// fill loads the pages from first to last that aren't cached yet, every
+// run of missing pages with a single read.
+func (c *PageCache) fill(first, last int64) {
+	for page := first; page <= last; page += pageSize {
+		if c.cached(page) {
+			continue
+		}
+
+		end := page
+		for end < last && !c.cached(end+pageSize) {
+			end += pageSize
+		}
+
+		c.load(page, end)
+		page = end
+	}
+}
+
+// load reads the pages from first to last. When a read of several pages
+// fails part way the rest is read page by page, a single unmapped page
+// fails the whole read on Windows.
+func (c *PageCache) load(first, last int64) {
+	buf := make([]byte, last-first+pageSize)
+	n, err := c.r.ReadAt(buf, first)
+
+	full := int64(n) &^ (pageSize - 1)
+	for i := int64(0); i < full; i += pageSize {
+		c.pages[first+i] = buf[i : i+pageSize]
+	}
+
+	rest := first + full
+	if rest > last {
+		return
+	}
+
+	if first != last {
+		for page := rest; page <= last; page += pageSize {
+			c.load(page, page)
+		}
+		return
+	}
+
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	c.errs[first] = err
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPageCache(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x2000)
+	p.putUint32(0x1010, 1)
+	p.putUint32(0x1ffe, 0x22221111)
+
+	c := NewPageCache(p)
+
+	if v, err := ReadUint32(c, 0x1010); err != nil || v != 1 {
+		t.Fatalf("ReadUint32 = %d, %v", v, err)
+	}
+
+	// across the page boundary, only the second page is read
+	if v, err := ReadUint32(c, 0x1ffe); err != nil || v != 0x22221111 {
+		t.Fatalf("ReadUint32 across pages = 0x%x, %v", v, err)
+	}
+
+	if p.reads != 2 {
+		t.Errorf("%d reads of the process, want 2", p.reads)
+	}
+
+	// values stay as they were until the next tick
+	p.putUint32(0x1010, 2)
+	if v, _ := ReadUint32(c, 0x1010); v != 1 || p.reads != 2 {
+		t.Errorf("cached ReadUint32 = %d after %d reads, want 1 after 2", v, p.reads)
+	}
+
+	c.Reset()
+	if v, _ := ReadUint32(c, 0x1010); v != 2 || p.reads != 3 {
+		t.Errorf("ReadUint32 after Reset = %d after %d reads, want 2 after 3", v, p.reads)
+	}
+}
+
+func TestPageCacheErrors(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+	p.mapRegion(0x3000, 0x1000)
+	p.putUint32(0x3000, 3)
+
+	c := NewPageCache(p)
+
+	// the unmapped page in the middle fails alone
+	b := make([]byte, 0x2004)
+	n, err := c.ReadAt(b, 0x1ffe)
+	if n != 2 || !errors.Is(err, ErrUnmapped) {
+		t.Errorf("ReadAt over a hole = %d, %v, want 2 bytes and ErrUnmapped", n, err)
+	}
+
+	if v, err := ReadUint32(c, 0x3000); err != nil || v != 3 {
+		t.Errorf("ReadUint32 behind the hole = %d, %v", v, err)
+	}
+
+	// failures are cached until the next tick too
+	reads := p.reads
+	if _, err := ReadUint32(c, 0x2010); !errors.Is(err, ErrUnmapped) || p.reads != reads {
+		t.Errorf("cached failure = %v after %d more reads", err, p.reads-reads)
+	}
+
+	p.mapRegion(0x2000, 0x1000)
+	c.Reset()
+	if _, err := ReadUint32(c, 0x2010); err != nil {
+		t.Errorf("ReadUint32 of a new page after Reset: %v", err)
+	}
+
+	// big reads bypass the cache
+	reads = p.reads
+	big := make([]byte, maxCachedRead+1)
+	if _, err := c.ReadAt(big, 0x1000); !errors.Is(err, ErrUnmapped) || p.reads != reads+1 {
+		t.Errorf("big ReadAt = %v after %d reads", err, p.reads-reads)
+	}
+	if !bytes.Equal(big[0x2000:0x2004], []byte{3, 0, 0, 0}) {
+		t.Errorf("big ReadAt read % X at 0x3000", big[0x2000:0x2004])
+	}
+}
+
+func TestPageCacheModules(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x400000, 0x1000).module = `C:\osu!\osu!.exe`
+	p.putUint32(0x400010, 7)
+
+	c := NewPageCache(p)
+
+	if v, err := Evaluate(c, &planAddresses{}, "[u32: osu!.exe + 0x10]"); err != nil || v != 7 {
+		t.Errorf("Evaluate through the cache = %d, %v", v, err)
+	}
+
+	// module bases survive the next tick
+	p.regions = nil
+	c.Reset()
+	if base, err := c.ModuleBase("OSU!.EXE"); err != nil || base != 0x400000 {
+		t.Errorf("ModuleBase after Reset = 0x%x, %v", base, err)
+	}
+}
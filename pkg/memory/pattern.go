@@ -0,0 +1,225 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// pattern is a parsed signature. A byte of memory b matches byte i if
+// b&mask[i] == bytes[i].
+//
+// Signatures are hex bytes separated by spaces. "??" (or "?") matches any
+// byte, a "?" in place of one digit matches any value of that nibble, so
+// "4?" matches 0x40 to 0x4F. Parentheses mark a capture:
+//
+//	5E 5F 5D C3 A1 ( ?? ?? ?? ?? ) 89 ?? 04
+//
+// resolves to the 4 byte little endian operand of the mov instead of the
+// address of the match. Captures can be 1, 2, 4 or 8 bytes long, an empty
+// capture "()" resolves to the address of its position in the match.
+//
+// A signed number after the last byte, like +0x4 or -2, is added to the
+// address the pattern resolves to.
+type pattern struct {
+	bytes []byte
+	mask  []byte
+
+	capture    bool
+	capStart   int
+	capEnd     int
+	adjust     int64
+	sourceText string
+}
+
+// tokenizePattern splits a signature at whitespace, parentheses are tokens
+// of their own even when written next to a byte.
+func tokenizePattern(s string) []string {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	return strings.Fields(s)
+}
+
+func parseNibble(c byte) (value, mask byte, ok bool) {
+	switch {
+	case c == '?':
+		return 0, 0, true
+	case c >= '0' && c <= '9':
+		return c - '0', 0xF, true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, 0xF, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, 0xF, true
+	default:
+		return 0, 0, false
+	}
+}
+
+func parsePattern(s string) (pattern, error) {
+	p := pattern{sourceText: s}
+	open := false
+	adjusted := false
+
+	fail := func(format string, args ...interface{}) (pattern, error) {
+		return pattern{}, fmt.Errorf("%w %q: %s", ErrInvalidPattern, s, fmt.Sprintf(format, args...))
+	}
+
+	for _, tok := range tokenizePattern(s) {
+		if adjusted {
+			return fail("%q after the adjustment", tok)
+		}
+
+		if tok[0] == '+' || tok[0] == '-' {
+			adjust, err := strconv.ParseInt(tok, 0, 64)
+			if err != nil {
+				return fail("%q is not an adjustment", tok)
+			}
+			p.adjust, adjusted = adjust, true
+			continue
+		}
+
+		switch tok {
+		case "(":
+			if p.capture {
+				return fail("more than one capture")
+			}
+			p.capture, open, p.capStart = true, true, len(p.bytes)
+			continue
+		case ")":
+			if !open {
+				return fail("unmatched )")
+			}
+			open, p.capEnd = false, len(p.bytes)
+			continue
+		case "?":
+			tok = "??"
+		}
+
+		if len(tok) != 2 {
+			return fail("%q is not a byte", tok)
+		}
+
+		hi, hiMask, ok1 := parseNibble(tok[0])
+		lo, loMask, ok2 := parseNibble(tok[1])
+		if !ok1 || !ok2 {
+			return fail("%q is not a byte", tok)
+		}
+
+		p.bytes = append(p.bytes, hi<<4|lo)
+		p.mask = append(p.mask, hiMask<<4|loMask)
+	}
+
+	if open {
+		return fail("unclosed (")
+	}
+
+	if len(p.bytes) == 0 {
+		return fail("empty")
+	}
+
+	switch p.capEnd - p.capStart {
+	case 0, 1, 2, 4, 8:
+	default:
+		return fail("capture of %d bytes, must be 1, 2, 4 or 8", p.capEnd-p.capStart)
+	}
+
+	fixed := false
+	for _, m := range p.mask {
+		fixed = fixed || m != 0
+	}
+
+	if !fixed {
+		return fail("only wildcards")
+	}
+
+	return p, nil
+}
+
+func (p pattern) String() string {
+	return p.sourceText
+}
+
+// match reports whether buf starts with the pattern.
+func (p pattern) match(buf []byte) bool {
+	if len(buf) < len(p.bytes) {
+		return false
+	}
+
+	for i := range p.bytes {
+		if buf[i]&p.mask[i] != p.bytes[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resolve turns the address of a match into the address the pattern
+// stands for, see pattern.
+func (p pattern) resolve(r io.ReaderAt, match int64) (int64, error) {
+	if !p.capture {
+		return match + p.adjust, nil
+	}
+
+	if p.capStart == p.capEnd {
+		return match + int64(p.capStart) + p.adjust, nil
+	}
+
+	v, err := readUintRaw(r, match+int64(p.capStart), p.capEnd-p.capStart)
+	return int64(v) + p.adjust, err
+}
+
+// parseSignature parses a sig tag, alternative patterns separated by "|"
+// that are tried in order. Each alternative can have its own adjustment:
+//
+//	8B 1D ( ?? ?? ?? ?? ) 85 DB | 8B 35 ( ?? ?? ?? ?? ) 85 F6 +0x4
+func parseSignature(sig string) ([]pattern, error) {
+	var alts []pattern
+
+	for _, alt := range strings.Split(sig, "|") {
+		pat, err := parsePattern(strings.TrimSpace(alt))
+		if err != nil {
+			return nil, err
+		}
+
+		alts = append(alts, pat)
+	}
+
+	return alts, nil
+}
+
+// anchor returns the longest run of bytes that are not masked at all. If
+// every byte is at least partly masked it returns the first byte that has
+// fixed bits, the scanner then searches for every value the byte can have.
+func (p pattern) anchor() (start, end int) {
+	for i := 0; i < len(p.mask); {
+		if p.mask[i] != 0xFF {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(p.mask) && p.mask[j] == 0xFF {
+			j++
+		}
+
+		if j-i > end-start {
+			start, end = i, j
+		}
+
+		i = j
+	}
+
+	if start == end {
+		for i, m := range p.mask {
+			if m != 0 {
+				return i, i + 1
+			}
+		}
+	}
+
+	return start, end
+}
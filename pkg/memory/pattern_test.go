@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		sig     string
+		bytes   []byte
+		mask    []byte
+		capture bool
+		cap     [2]int
+		adjust  int64
+	}{
+		{sig: "8B 1D", bytes: []byte{0x8B, 0x1D}, mask: []byte{0xFF, 0xFF}},
+		{sig: "8b 1d", bytes: []byte{0x8B, 0x1D}, mask: []byte{0xFF, 0xFF}},
+		{sig: "8B ?? 1D", bytes: []byte{0x8B, 0, 0x1D}, mask: []byte{0xFF, 0, 0xFF}},
+		{sig: "8B ? 1D", bytes: []byte{0x8B, 0, 0x1D}, mask: []byte{0xFF, 0, 0xFF}},
+		{sig: "4? ?5", bytes: []byte{0x40, 0x05}, mask: []byte{0xF0, 0x0F}},
+		{
+			sig:   "A1 ( ?? ?? ?? ?? ) 89",
+			bytes: []byte{0xA1, 0, 0, 0, 0, 0x89}, mask: []byte{0xFF, 0, 0, 0, 0, 0xFF},
+			capture: true, cap: [2]int{1, 5},
+		},
+		{
+			sig:   "A1 (?? ??) 89",
+			bytes: []byte{0xA1, 0, 0, 0x89}, mask: []byte{0xFF, 0, 0, 0xFF},
+			capture: true, cap: [2]int{1, 3},
+		},
+		{
+			sig:   "A1(??)",
+			bytes: []byte{0xA1, 0}, mask: []byte{0xFF, 0},
+			capture: true, cap: [2]int{1, 2},
+		},
+		{
+			sig:   "A1 ?? ?? ?? ?? ?? ?? ?? ?? ( ) 89",
+			bytes: []byte{0xA1, 0, 0, 0, 0, 0, 0, 0, 0, 0x89}, mask: []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF},
+			capture: true, cap: [2]int{9, 9},
+		},
+		{sig: "F8 01 +0x4", bytes: []byte{0xF8, 0x01}, mask: []byte{0xFF, 0xFF}, adjust: 4},
+		{sig: "F8 01 -2", bytes: []byte{0xF8, 0x01}, mask: []byte{0xFF, 0xFF}, adjust: -2},
+		{
+			sig:   "A1 ( ?? ?? ?? ?? ) -0xC",
+			bytes: []byte{0xA1, 0, 0, 0, 0}, mask: []byte{0xFF, 0, 0, 0, 0},
+			capture: true, cap: [2]int{1, 5}, adjust: -0xC,
+		},
+	}
+
+	for _, tt := range tests {
+		p, err := parsePattern(tt.sig)
+		if err != nil {
+			t.Errorf("parsePattern(%q): %v", tt.sig, err)
+			continue
+		}
+
+		if !bytes.Equal(p.bytes, tt.bytes) || !bytes.Equal(p.mask, tt.mask) {
+			t.Errorf("parsePattern(%q) = % X mask % X, want % X mask % X", tt.sig, p.bytes, p.mask, tt.bytes, tt.mask)
+		}
+
+		if p.capture != tt.capture || p.capStart != tt.cap[0] || p.capEnd != tt.cap[1] {
+			t.Errorf("parsePattern(%q) capture %v %d-%d, want %v %d-%d",
+				tt.sig, p.capture, p.capStart, p.capEnd, tt.capture, tt.cap[0], tt.cap[1])
+		}
+
+		if p.adjust != tt.adjust {
+			t.Errorf("parsePattern(%q) adjust %d, want %d", tt.sig, p.adjust, tt.adjust)
+		}
+	}
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"?? ??",
+		"8B 1",
+		"8B 1D2",
+		"8B G1",
+		"8B 1D )",
+		"8B ( 1D",
+		"( 8B ) ( 1D )",
+		"8B ( ?? ?? ?? )",
+		"8B ( ?? ?? ?? ?? ?? )",
+		"8B +4 1D",
+		"8B +4 -4",
+		"8B +x",
+		"8B -",
+	}
+
+	for _, sig := range tests {
+		if _, err := parsePattern(sig); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("parsePattern(%q) = %v, want ErrInvalidPattern", sig, err)
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		sig   string
+		buf   []byte
+		match bool
+	}{
+		{"8B 1D", []byte{0x8B, 0x1D, 0x00}, true},
+		{"8B 1D", []byte{0x8B, 0x1C}, false},
+		{"8B 1D", []byte{0x8B}, false},
+		{"8B ?? 1D", []byte{0x8B, 0x42, 0x1D}, true},
+		{"4? 05", []byte{0x40, 0x05}, true},
+		{"4? 05", []byte{0x4F, 0x05}, true},
+		{"4? 05", []byte{0x50, 0x05}, false},
+		{"?5", []byte{0xA5}, true},
+		{"?5", []byte{0xA6}, false},
+	}
+
+	for _, tt := range tests {
+		p, err := parsePattern(tt.sig)
+		if err != nil {
+			t.Fatalf("parsePattern(%q): %v", tt.sig, err)
+		}
+
+		if got := p.match(tt.buf); got != tt.match {
+			t.Errorf("%q.match(% X) = %v, want %v", tt.sig, tt.buf, got, tt.match)
+		}
+	}
+}
+
+func TestPatternResolve(t *testing.T) {
+	mem := bytes.NewReader([]byte{
+		0x00, 0x00, 0x00, 0x00, // padding, the match is at 4
+		0xA1, 0x78, 0x56, 0x34, 0x12, 0x89, 0x10, 0x20, 0x30,
+	})
+
+	tests := []struct {
+		sig  string
+		want int64
+	}{
+		{"A1", 4},
+		{"A1 +0x10", 0x14},
+		{"A1 -4", 0},
+		{"A1 ( ) 78", 5},
+		{"A1 ?? ( ) 56 +1", 7},
+		{"A1 ( ?? ) 56", 0x78},
+		{"A1 ( ?? ?? ) 34", 0x5678},
+		{"A1 ( ?? ?? ?? ?? ) 89", 0x12345678},
+		{"A1 ( ?? ?? ?? ?? ) 89 +0x8", 0x12345680},
+		{"A1 ( ?? ?? ?? ?? ?? ?? ?? ?? )", 0x3020108912345678},
+	}
+
+	for _, tt := range tests {
+		p, err := parsePattern(tt.sig)
+		if err != nil {
+			t.Fatalf("parsePattern(%q): %v", tt.sig, err)
+		}
+
+		got, err := p.resolve(mem, 4)
+		if err != nil || got != tt.want {
+			t.Errorf("%q.resolve = 0x%X, %v, want 0x%X", tt.sig, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	alts, err := parseSignature("8B 1D ( ?? ?? ?? ?? ) 85 DB | 8B 35 ( ?? ?? ?? ?? ) 85 F6 +0x4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	var adjusts []int64
+	for _, alt := range alts {
+		texts = append(texts, alt.String())
+		adjusts = append(adjusts, alt.adjust)
+	}
+
+	wantTexts := []string{"8B 1D ( ?? ?? ?? ?? ) 85 DB", "8B 35 ( ?? ?? ?? ?? ) 85 F6 +0x4"}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("alternatives %q, want %q", texts, wantTexts)
+	}
+
+	if wantAdjusts := []int64{0, 4}; !reflect.DeepEqual(adjusts, wantAdjusts) {
+		t.Errorf("adjustments %v, want %v", adjusts, wantAdjusts)
+	}
+
+	for _, sig := range []string{"8B 1D |", "| 8B 1D", "8B 1D | 8B +4 1D"} {
+		if _, err := parseSignature(sig); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("parseSignature(%q) = %v, want ErrInvalidPattern", sig, err)
+		}
+	}
+}
@@ -0,0 +1,834 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// selfVar is the variable holding the address of the struct a nested field
+// belongs to.
+const selfVar = "Self"
+
+// Plan reads the `memory:"..."` fields of a struct, see Compile. A Plan can
+// be used by several goroutines at once.
+type Plan struct {
+	addrType reflect.Type
+	dataType reflect.Type
+
+	// derefs are the addresses read as pointers. Every expression only
+	// refers to derefs before it, prefixes shared by several fields are
+	// stored once.
+	derefs []planDeref
+	fields []planField
+}
+
+// planExpr is a compiled mem, the sum of offset and its terms. hops is the
+// length of the longest pointer chain in it.
+type planExpr struct {
+	offset int64
+	terms  []planTerm
+	hops   int
+}
+
+// planDeref reads the value at the address planExpr. size is 0 for
+// pointers, which take the size of the reader's layout.
+type planDeref struct {
+	planExpr
+	size   int
+	signed bool
+	null   bool
+}
+
+// planTerm is scale times the value read by derefs[deref], the base address
+// of module, the address of the slice element being read if self is set, or
+// else the addresses field at index field.
+type planTerm struct {
+	scale  int64
+	deref  int
+	module string
+	self   bool
+	field  []int
+}
+
+// planField is a field read by a plan. The fields of nested structs are
+// flattened into the plan of their parent. Slices read every element with
+// elem, or with its UnmarshalMemory if unmarshal is set.
+type planField struct {
+	index    []int
+	name     string
+	expr     string
+	addr     planExpr
+	optional bool
+
+	unmarshal  bool
+	validators []validator
+
+	slice bool
+	elem  *Plan
+	array bool
+}
+
+type planCompiler struct {
+	plan      *Plan
+	methods   reflect.Value
+	derefs    map[string]int
+	expanding map[string]bool
+
+	// self is the address of the struct whose fields are compiled, nil at
+	// the top level. element is set when compiling the element of a slice,
+	// Self is then only known when reading.
+	self     *mem
+	element  bool
+	elements map[reflect.Type]bool
+
+	// defs replace tags, used are the fields of plan.dataType they defined
+	defs *Definitions
+	used map[string]bool
+}
+
+// Compile parses the memory tags of the fields of dataType once so the
+// struct can be read every tick without parsing them again. addresses and
+// dataType are structs or pointers to structs, only their types are used.
+//
+// Tags are sums of integers, variables and dereferences. [X] reads the
+// pointer at X, [u64: X] reads a value of the given width, [?X] reads it
+// null safely so a null pointer leaves the field at zero instead of failing.
+// Constants can be multiplied and names with a dot, like osu!.exe, are the
+// base addresses of modules.
+//
+// Variables in the tags are int64 fields of addresses, resolved when the
+// plan is read, or methods of addresses returning another expression. The
+// methods are called once by Compile and have to return the same expression
+// every time.
+//
+// A field can be a struct, its tag is then the address of the struct and
+// the tags of its fields refer to that address as Self:
+//
+//	Score struct {
+//		Combo  int16  `memory:"Self + 0x94"`
+//		Player string `memory:"[Self + 0x28]"`
+//	} `memory:"[[Ruleset + 0x68] + 0x38]"`
+//
+// A slice of structs is read from the List<T> at the address of the field,
+// every element is a reference and Self is the object it points to.
+//
+// Types implementing MemoryUnmarshaler decode themselves from the address
+// of the field, slices of them from the reference of every element.
+//
+// Numbers and slices of numbers can have a validate tag next to their
+// memory tag, values breaking it fail the field with ErrImplausible:
+//
+//	Accuracy float64 `memory:"[Score + 0xC]" validate:"range=0,100"`
+//	HitMiss  int16   `memory:"Self + 0x92" validate:"nonneg"`
+//
+// Options follow the expression separated by commas:
+//
+//	optional  the pointer chain may be dead, failing to read the field is
+//	          reported in ReadError.Optional. Applies to nested fields too.
+//	array     read a struct slice from a T[] instead of a List<T>
+func Compile(addresses interface{}, dataType interface{}) (*Plan, error) {
+	return CompileDefinitions(addresses, dataType, nil)
+}
+
+// CompileDefinitions is like Compile but uses the tags and variables of defs
+// where they are defined. Fields defs defines for dataType that don't exist
+// are an error, struct names no plan reads are caught by
+// Definitions.CheckFields once every plan is compiled.
+func CompileDefinitions(addresses interface{}, dataType interface{}, defs *Definitions) (*Plan, error) {
+	addrType := structType(addresses)
+	valueType := structType(dataType)
+
+	if addrType == nil {
+		panic("addresses must be a struct or a pointer to a struct")
+	}
+
+	if valueType == nil {
+		panic("dataType must be a struct or a pointer to a struct")
+	}
+
+	c := &planCompiler{
+		plan:      &Plan{addrType: addrType, dataType: valueType},
+		methods:   reflect.New(addrType),
+		derefs:    map[string]int{},
+		expanding: map[string]bool{},
+		elements:  map[reflect.Type]bool{valueType: true},
+		defs:      defs,
+		used:      map[string]bool{},
+	}
+
+	if err := c.compileStruct(valueType, nil, "", nil, false); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkUsed(); err != nil {
+		return nil, err
+	}
+
+	return c.plan, nil
+}
+
+// structNames adds the names of the struct types plan reads to names, its
+// own and those of the elements of struct slices.
+func (plan *Plan) structNames(names map[string]bool) {
+	names[plan.dataType.Name()] = true
+
+	for _, f := range plan.fields {
+		if f.elem != nil {
+			f.elem.structNames(names)
+		}
+	}
+}
+
+// checkUsed fails if defs define fields of plan.dataType that don't exist.
+func (c *planCompiler) checkUsed() error {
+	if c.defs == nil {
+		return nil
+	}
+
+	name := c.plan.dataType.Name()
+	for path := range c.defs.Fields[name] {
+		if !c.used[path] {
+			return fmt.Errorf("definitions: %s has no field %s", name, path)
+		}
+	}
+
+	return nil
+}
+
+// compileStruct adds the tagged fields of t to the plan. index and prefix
+// lead from the plan's struct to t, self is the address of t.
+func (c *planCompiler) compileStruct(t reflect.Type, index []int, prefix string, self *mem, optional bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		fieldT := t.Field(i)
+
+		tag, ok := fieldT.Tag.Lookup("memory")
+		if defined, found := c.defs.field(c.plan.dataType.Name(), prefix+fieldT.Name); found {
+			tag, ok = defined, true
+			c.used[prefix+fieldT.Name] = true
+		}
+
+		if !ok {
+			continue
+		}
+
+		exprStr, opts, _ := strings.Cut(tag, ",")
+		field := planField{
+			index:    append(append([]int(nil), index...), i),
+			name:     prefix + fieldT.Name,
+			expr:     strings.TrimSpace(exprStr),
+			optional: optional,
+		}
+
+		tagError := func(err error) error {
+			return fmt.Errorf("failed to parse memory tag for %s.%s: %w", c.plan.dataType.Name(), field.name, err)
+		}
+
+		for _, opt := range strings.Split(opts, ",") {
+			switch strings.TrimSpace(opt) {
+			case "":
+			case "optional":
+				field.optional = true
+			case "array":
+				field.array = true
+			default:
+				return tagError(fmt.Errorf("unknown option %q", opt))
+			}
+		}
+
+		if !fieldT.IsExported() {
+			return fmt.Errorf("cannot read %s.%s: unexported field", c.plan.dataType.Name(), field.name)
+		}
+
+		c.self = self
+
+		expr, err := parseMem(exprStr)
+		if err == nil {
+			expr, err = expr.expand(c.lookup)
+		}
+		if err != nil {
+			return tagError(err)
+		}
+
+		if field.validators, err = parseValidators(fieldT.Tag.Get("validate")); err != nil {
+			return fmt.Errorf("failed to parse validate tag for %s.%s: %w", c.plan.dataType.Name(), field.name, err)
+		}
+
+		if len(field.validators) != 0 && !isNumeric(fieldT.Type) {
+			return fmt.Errorf("cannot validate %s.%s: %s is not a number", c.plan.dataType.Name(), field.name, fieldT.Type)
+		}
+
+		switch typ := fieldT.Type; {
+		case isUnmarshaler(typ):
+			field.unmarshal = true
+		case isPrimitive(typ):
+		case typ.Kind() == reflect.Slice && isUnmarshaler(typ.Elem()):
+			field.slice, field.unmarshal = true, true
+		case typ.Kind() == reflect.Struct:
+			if err := c.compileStruct(typ, field.index, field.name+".", expr, field.optional); err != nil {
+				return err
+			}
+			continue
+		case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Struct:
+			field.slice = true
+			if field.elem, err = c.compileElement(typ.Elem()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("cannot read %s.%s: unsupported field of type %s", c.plan.dataType.Name(), field.name, typ)
+		}
+
+		if field.array && !field.slice {
+			return tagError(errors.New("array only applies to slices of structs"))
+		}
+
+		field.addr = c.compile(expr)
+		c.plan.fields = append(c.plan.fields, field)
+	}
+
+	return nil
+}
+
+// compileElement compiles the plan reading one element of a struct slice.
+func (c *planCompiler) compileElement(t reflect.Type) (*Plan, error) {
+	if c.elements[t] {
+		return nil, fmt.Errorf("cannot read %s: %s contains itself", c.plan.dataType.Name(), t)
+	}
+
+	c.elements[t] = true
+	defer delete(c.elements, t)
+
+	ec := &planCompiler{
+		plan:      &Plan{addrType: c.plan.addrType, dataType: t},
+		methods:   c.methods,
+		derefs:    map[string]int{},
+		expanding: map[string]bool{},
+		element:   true,
+		elements:  c.elements,
+		defs:      c.defs,
+		used:      map[string]bool{},
+	}
+
+	self := &mem{Terms: []memTerm{{Scale: 1, Var: selfVar}}}
+	if err := ec.compileStruct(t, nil, "", self, false); err != nil {
+		return nil, err
+	}
+
+	if err := ec.checkUsed(); err != nil {
+		return nil, err
+	}
+
+	return ec.plan, nil
+}
+
+func structType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
+
+var unmarshalerType = reflect.TypeOf((*MemoryUnmarshaler)(nil)).Elem()
+
+func isUnmarshaler(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(unmarshalerType)
+}
+
+func isPrimitive(t reflect.Type) bool {
+	switch reflect.New(t).Interface().(type) {
+	case *bool, *int8, *int16, *int32, *int64, *uint8, *uint16, *uint32, *uint64, *float32, *float64,
+		*[]int8, *[]int16, *[]int32, *[]int64, *[]uint8, *[]uint16, *[]uint32, *[]uint64,
+		*[]float32, *[]float64, *string:
+		return true
+	default:
+		return false
+	}
+}
+
+// lookup expands Self, the variables of the definitions and the methods of
+// the addresses struct and checks that fields are int64.
+func (c *planCompiler) lookup(name string) (*mem, error) {
+	if name == selfVar && c.self != nil {
+		return c.self, nil
+	}
+
+	if expr, ok := c.defs.variable(name); ok {
+		return c.expandVariable(name, expr)
+	}
+
+	if field, ok := c.plan.addrType.FieldByName(name); ok {
+		if field.Type.Kind() != reflect.Int64 {
+			return nil, fmt.Errorf("variable %s is a %s, not an int64", name, field.Type)
+		}
+		return nil, nil
+	}
+
+	method := c.methods.MethodByName(name)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("undefined variable %s", name)
+	}
+
+	if method.Type().NumIn() != 0 || method.Type().NumOut() != 1 || method.Type().Out(0).Kind() != reflect.String {
+		return nil, fmt.Errorf("variable %s is a method, but not a func() string", name)
+	}
+
+	return c.expandVariable(name, method.Call(nil)[0].String())
+}
+
+// expandVariable parses and expands the expression of the variable name.
+func (c *planCompiler) expandVariable(name, exprStr string) (*mem, error) {
+	if c.expanding[name] {
+		return nil, fmt.Errorf("variable %s refers to itself", name)
+	}
+
+	c.expanding[name] = true
+	defer delete(c.expanding, name)
+
+	expr, err := parseMem(exprStr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return expr.expand(c.lookup)
+}
+
+// compile turns an expanded mem into a planExpr, sharing dereferences
+// between fields.
+func (c *planCompiler) compile(m *mem) planExpr {
+	e := planExpr{offset: m.Offset}
+
+	for _, t := range m.Terms {
+		term := planTerm{scale: t.Scale, deref: -1}
+
+		switch {
+		case t.Deref != nil:
+			term.deref = c.deref(t)
+			if hops := c.plan.derefs[term.deref].hops + 1; hops > e.hops {
+				e.hops = hops
+			}
+		case t.Module != "":
+			term.module = t.Module
+		case t.Var == selfVar && c.element:
+			term.self = true
+		default:
+			field, _ := c.plan.addrType.FieldByName(t.Var)
+			term.field = field.Index
+		}
+
+		e.terms = append(e.terms, term)
+	}
+
+	return e
+}
+
+// deref returns the index of the dereference t in the plan, the width and
+// null safety of t are part of its key.
+func (c *planCompiler) deref(t memTerm) int {
+	key := t.String()
+	if i, ok := c.derefs[key]; ok {
+		return i
+	}
+
+	width := derefWidths[t.Width]
+	d := planDeref{
+		planExpr: c.compile(t.Deref),
+		size:     width.size,
+		signed:   width.signed,
+		null:     t.Null,
+	}
+
+	c.plan.derefs = append(c.plan.derefs, d)
+	c.derefs[key] = len(c.plan.derefs) - 1
+
+	return len(c.plan.derefs) - 1
+}
+
+// planExec holds the pointers read during one Plan.Read, or while reading
+// one element of a struct slice. A null deref is a null safe one that hit a
+// null pointer.
+type planExec struct {
+	plan  *Plan
+	r     io.ReaderAt
+	addrs reflect.Value
+	self  int64
+
+	done  []bool
+	vals  []int64
+	nulls []bool
+	errs  []*hopError
+}
+
+func newPlanExec(plan *Plan, r io.ReaderAt, addrs reflect.Value, self int64) *planExec {
+	return &planExec{
+		plan:  plan,
+		r:     r,
+		addrs: addrs,
+		self:  self,
+		done:  make([]bool, len(plan.derefs)),
+		vals:  make([]int64, len(plan.derefs)),
+		nulls: make([]bool, len(plan.derefs)),
+		errs:  make([]*hopError, len(plan.derefs)),
+	}
+}
+
+// hopError is a failed dereference, the hop is counted like
+// FieldError.Hop.
+type hopError struct {
+	hop  int
+	addr int64
+	err  error
+}
+
+// eval returns the address e points to, or null if it goes through a null
+// safe dereference of a null pointer.
+func (ex *planExec) eval(e planExpr) (addr int64, null bool, herr *hopError) {
+	addr = e.offset
+
+	for _, t := range e.terms {
+		var v int64
+
+		switch {
+		case t.deref >= 0:
+			if !ex.done[t.deref] {
+				ex.vals[t.deref], ex.nulls[t.deref], ex.errs[t.deref] = ex.derefAt(t.deref)
+				ex.done[t.deref] = true
+			}
+
+			if ex.errs[t.deref] != nil || ex.nulls[t.deref] {
+				return 0, ex.nulls[t.deref], ex.errs[t.deref]
+			}
+			v = ex.vals[t.deref]
+		case t.module != "":
+			base, err := moduleBase(ex.r, t.module)
+			if err != nil {
+				return 0, false, &hopError{err: err}
+			}
+			v = base
+		case t.self:
+			v = ex.self
+		default:
+			v = ex.addrs.FieldByIndex(t.field).Int()
+		}
+
+		addr += t.scale * v
+	}
+
+	return addr, false, nil
+}
+
+func (ex *planExec) derefAt(i int) (int64, bool, *hopError) {
+	d := ex.plan.derefs[i]
+
+	addr, null, herr := ex.eval(d.planExpr)
+	if herr != nil || null {
+		return 0, null, herr
+	}
+
+	size := d.size
+	if size == 0 {
+		size = layoutOf(ex.r).PointerSize
+	}
+
+	v, err := readUintRaw(ex.r, addr, size)
+	if err != nil {
+		if d.null {
+			return 0, true, nil
+		}
+		return 0, false, ex.failed(d.hops, addr, err)
+	}
+
+	if d.null && v == 0 {
+		return 0, true, nil
+	}
+
+	if d.signed {
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, false, nil
+	}
+
+	return int64(v), false, nil
+}
+
+// failed describes a read at addr after hop pointers that failed with err.
+func (ex *planExec) failed(hop int, addr int64, err error) *hopError {
+	return &hopError{hop: hop, addr: addr, err: pointerError(ex.cause(err), addr, hop)}
+}
+
+// cause turns read errors of a process that is gone into ErrProcessExited.
+func (ex *planExec) cause(err error) error {
+	if errors.Is(err, ErrUnmapped) && !isAlive(ex.r) {
+		return fmt.Errorf("%w (%w)", ErrProcessExited, err)
+	}
+
+	return err
+}
+
+// Read reads the fields of p, a pointer to the struct the plan was compiled
+// for, using the addresses in addresses. Every pointer is read at most once.
+// Fields that can't be read are set to zero and listed in the returned
+// ReadError, the other fields are read regardless.
+func (plan *Plan) Read(r io.ReaderAt, addresses interface{}, p interface{}) error {
+	addrVal := reflect.Indirect(reflect.ValueOf(addresses))
+	if addrVal.Type() != plan.addrType {
+		panic(fmt.Sprintf("plan is compiled for addresses of type %s, not %s", plan.addrType, addrVal.Type()))
+	}
+
+	pVal := reflect.ValueOf(p)
+	if pVal.Kind() != reflect.Ptr || pVal.Elem().Type() != plan.dataType {
+		panic(fmt.Sprintf("plan is compiled for *%s, not %s", plan.dataType, pVal.Type()))
+	}
+
+	var errs ReadError
+
+	ex := newPlanExec(plan, r, addrVal, 0)
+	ex.read(pVal.Elem(), plan.dataType.Name(), "", false, &errs)
+
+	if len(errs.Required) != 0 || len(errs.Optional) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// read reads the fields of val, the struct ex.plan was compiled for. Failed
+// fields are added to errs, named prefix followed by their own name.
+func (ex *planExec) read(val reflect.Value, structName, prefix string, optional bool, errs *ReadError) {
+	for _, f := range ex.plan.fields {
+		field := val.FieldByIndex(f.index)
+
+		addr, null, herr := ex.eval(f.addr)
+		if null {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+
+		if herr == nil {
+			var err error
+
+			switch {
+			case f.slice:
+				herr = ex.readElements(f, field, addr, structName, prefix+f.name, optional || f.optional, errs)
+			case f.unmarshal:
+				err = field.Addr().Interface().(MemoryUnmarshaler).UnmarshalMemory(ex.r, addr)
+			default:
+				err = readPrimitive(ex.r, field.Addr().Interface(), addr, 0)
+			}
+
+			if err != nil {
+				herr = ex.failed(f.addr.hops, addr, err)
+			} else if herr == nil && len(f.validators) != 0 {
+				if err := validate(field, f.validators); err != nil {
+					herr = &hopError{hop: f.addr.hops, addr: addr, err: err}
+				}
+			}
+		}
+
+		if herr == nil {
+			continue
+		}
+
+		field.Set(reflect.Zero(field.Type()))
+
+		errs.add(&FieldError{
+			Struct:   structName,
+			Field:    prefix + f.name,
+			Expr:     f.expr,
+			Hop:      herr.hop,
+			Addr:     herr.addr,
+			Optional: optional || f.optional,
+			Err:      herr.err,
+		})
+	}
+}
+
+// readElements reads the slice f from the List<T>, or T[] if f.array is
+// set, at addr. Elements that fail are reported on their own,
+// only a broken list fails the whole field.
+func (ex *planExec) readElements(f planField, field reflect.Value, addr int64,
+	structName, name string, optional bool, errs *ReadError) *hopError {
+	layout := layoutOf(ex.r)
+	items := addr
+
+	var length int32
+	var err error
+
+	if f.array {
+		length, err = ReadInt32(ex.r, addr, layout.ArrayLength)
+	} else {
+		length, err = ReadInt32(ex.r, addr, layout.ListSize)
+		if err == nil {
+			items, err = ReadPtr(ex.r, addr, layout.ListItems)
+		}
+	}
+
+	switch {
+	case err != nil:
+	case length < 0:
+		err = ErrInvalidArrayLength
+	case length > MaxArrayLength:
+		err = ErrArrayTooLong
+	}
+
+	if err != nil {
+		return ex.failed(f.addr.hops, addr, err)
+	}
+
+	buf := make([]byte, int(length)*layout.PointerSize)
+	if _, err := readFullAt(ex.r, buf, items+layout.ArrayData); err != nil {
+		return ex.failed(f.addr.hops+1, items+layout.ArrayData, err)
+	}
+
+	slice := reflect.MakeSlice(field.Type(), int(length), int(length))
+	for i := 0; i < int(length); i++ {
+		ptr := int64(bytesToInt(buf[i*layout.PointerSize : (i+1)*layout.PointerSize]))
+
+		if f.elem != nil {
+			elem := newPlanExec(f.elem, ex.r, ex.addrs, ptr)
+			elem.read(slice.Index(i), structName, fmt.Sprintf("%s[%d].", name, i), optional, errs)
+			continue
+		}
+
+		if err := slice.Index(i).Addr().Interface().(MemoryUnmarshaler).UnmarshalMemory(ex.r, ptr); err != nil {
+			errs.add(&FieldError{
+				Struct:   structName,
+				Field:    fmt.Sprintf("%s[%d]", name, i),
+				Expr:     f.expr,
+				Addr:     ptr,
+				Optional: optional,
+				Err:      ex.cause(err),
+			})
+		}
+	}
+
+	field.Set(slice)
+	return nil
+}
+
+// Evaluate returns the address expr stands for in r, with the variables of
+// addresses like in a memory tag. A null safe dereference of a null
+// pointer makes it 0.
+func Evaluate(r io.ReaderAt, addresses interface{}, expr string) (int64, error) {
+	return EvaluateDefinitions(r, addresses, expr, nil)
+}
+
+// EvaluateDefinitions is like Evaluate but uses the variables of defs where
+// they are defined, like CompileDefinitions.
+func EvaluateDefinitions(r io.ReaderAt, addresses interface{}, expr string, defs *Definitions) (int64, error) {
+	addrVal := reflect.Indirect(reflect.ValueOf(addresses))
+	if addrVal.Kind() != reflect.Struct {
+		panic("addresses must be a struct or a pointer to a struct")
+	}
+
+	c := newVariableCompiler(addrVal.Type(), defs)
+
+	m, err := parseMem(expr)
+	if err == nil {
+		m, err = m.expand(c.lookup)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", expr, err)
+	}
+
+	e := c.compile(m)
+
+	addr, _, herr := newPlanExec(c.plan, r, addrVal, 0).eval(e)
+	if herr != nil {
+		return 0, fmt.Errorf("failed to evaluate %s (hop %d at 0x%x): %w", expr, herr.hop, herr.addr, herr.err)
+	}
+
+	return addr, nil
+}
+
+// Variables returns the sorted names of the variables memory tags compiled
+// with addresses and defs can refer to: the int64 fields of addresses, its
+// methods returning an expression and the variables of defs. Variables
+// whose expression refers to undefined ones are left out.
+func Variables(addresses interface{}, defs *Definitions) []string {
+	t := structType(addresses)
+	if t == nil {
+		panic("addresses must be a struct or a pointer to a struct")
+	}
+
+	var candidates []string
+	for _, f := range reflect.VisibleFields(t) {
+		if f.IsExported() && !f.Anonymous && f.Type.Kind() == reflect.Int64 {
+			candidates = append(candidates, f.Name)
+		}
+	}
+
+	methods := reflect.PointerTo(t)
+	for i := 0; i < methods.NumMethod(); i++ {
+		candidates = append(candidates, methods.Method(i).Name)
+	}
+
+	if defs != nil {
+		for name := range defs.Variables {
+			candidates = append(candidates, name)
+		}
+	}
+
+	c := newVariableCompiler(t, defs)
+	seen := map[string]bool{}
+	var names []string
+
+	for _, name := range candidates {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if _, err := c.lookup(name); err == nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// newVariableCompiler returns a compiler for expressions outside of memory
+// tags, which have no Self.
+func newVariableCompiler(addrType reflect.Type, defs *Definitions) *planCompiler {
+	return &planCompiler{
+		plan:      &Plan{addrType: addrType},
+		methods:   reflect.New(addrType),
+		derefs:    map[string]int{},
+		expanding: map[string]bool{},
+		defs:      defs,
+	}
+}
+
+// plans caches the plans compiled by Read, keyed by planKey.
+var plans sync.Map
+
+type planKey struct {
+	addrType reflect.Type
+	dataType reflect.Type
+}
+
+// cachedPlan returns the plan for the types of addresses and p, compiling it
+// on first use.
+func cachedPlan(addresses interface{}, p interface{}) (*Plan, error) {
+	key := planKey{structType(addresses), structType(p)}
+	if plan, ok := plans.Load(key); ok {
+		return plan.(*Plan), nil
+	}
+
+	plan, err := Compile(addresses, p)
+	if err != nil {
+		return nil, err
+	}
+
+	plans.Store(key, plan)
+	return plan, nil
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+)
+
+// planAddresses has the object all test structs are read from at [Base].
+type planAddresses struct {
+	Base int64
+}
+
+func (planAddresses) Root() string {
+	return "[Base]"
+}
+
+type planData struct {
+	Count int32  `memory:"Root + 0x4"`
+	Delta int16  `memory:"Root + 0x8"`
+	Name  string `memory:"[Root + 0xC]"`
+	Dead  int32  `memory:"[Root + 0x10] + 0x4,optional"`
+	Null  int32  `memory:"[?Root + 0x14] + 0x4"`
+}
+
+// newPlanProcess maps the object at [Base] = 0x1100 and the string "osu"
+// it refers to at +0xC. The pointer at +0x10 is dead, the one at +0x14 is
+// null.
+func newPlanProcess() (*fakeProcess, *planAddresses) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+
+	p.putPtr(0x1000, 0x1100)
+	p.putUint32(0x1104, 42)
+	p.write(0x1108, []byte{0xf9, 0xff})
+	p.putPtr(0x110c, 0x1200)
+	p.putPtr(0x1110, 0x9000)
+	putString(p, 0x1200, "osu")
+
+	return p, &planAddresses{Base: 0x1000}
+}
+
+func TestPlanSharedDerefs(t *testing.T) {
+	plan, err := Compile(&planAddresses{}, &planData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// [Base] is read once for every field
+	if len(plan.derefs) != 4 {
+		t.Errorf("plan has %d dereferences, want 4", len(plan.derefs))
+	}
+
+	p, addrs := newPlanProcess()
+	data := planData{Dead: 5, Null: 6}
+
+	err = plan.Read(p, addrs, &data)
+
+	want := planData{Count: 42, Delta: -7, Name: "osu"}
+	if data != want {
+		t.Errorf("read %+v, want %+v", data, want)
+	}
+
+	if !OnlyOptional(err) {
+		t.Fatalf("Read = %v, want only the optional field to fail", err)
+	}
+
+	var rerr ReadError
+	errors.As(err, &rerr)
+
+	if len(rerr.Optional) != 1 {
+		t.Fatalf("optional errors %v, want Dead", rerr.Optional)
+	}
+
+	ferr := rerr.Optional[0]
+	if ferr.Struct != "planData" || ferr.Field != "Dead" || ferr.Hop != 2 || ferr.Addr != 0x9004 ||
+		!ferr.Optional || !errors.Is(ferr, ErrUnmapped) {
+		t.Errorf("optional error %+v", ferr)
+	}
+}
+
+func TestPlanRequiredErrors(t *testing.T) {
+	type data struct {
+		Count int32  `memory:"Root + 0x4"`
+		Gone  int32  `memory:"[Root + 0x10]"`
+		Far   string `memory:"[Root + 0x2000]"`
+	}
+
+	p, addrs := newPlanProcess()
+
+	var d data
+	err := Read(p, addrs, &d)
+
+	if d.Count != 42 {
+		t.Errorf("Count = %d, want 42 despite the failed fields", d.Count)
+	}
+
+	var rerr ReadError
+	if !errors.As(err, &rerr) || OnlyOptional(err) || len(rerr.Required) != 2 {
+		t.Fatalf("Read = %v, want Gone and Far to fail", err)
+	}
+
+	tests := []struct {
+		field string
+		hop   int
+		addr  int64
+	}{
+		{"Gone", 2, 0x9000},
+		{"Far", 1, 0x3100},
+	}
+
+	for i, tt := range tests {
+		ferr := rerr.Required[i]
+		if ferr.Field != tt.field || ferr.Hop != tt.hop || ferr.Addr != tt.addr || ferr.Optional {
+			t.Errorf("error %d = %+v, want %s at hop %d, 0x%x", i, ferr, tt.field, tt.hop, tt.addr)
+		}
+	}
+
+	p.exited = true
+	if err := Read(p, addrs, &d); !errors.Is(err, ErrProcessExited) {
+		t.Errorf("Read after exit = %v, want ErrProcessExited", err)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+	}{
+		{"undefined variable", &struct {
+			A int32 `memory:"Missing + 0x4"`
+		}{}},
+		{"unknown option", &struct {
+			A int32 `memory:"Root + 0x4,sometimes"`
+		}{}},
+		{"array of a number", &struct {
+			A int32 `memory:"Root + 0x4,array"`
+		}{}},
+		{"unsupported type", &struct {
+			A map[int]int `memory:"Root + 0x4"`
+		}{}},
+		{"unexported field", &struct {
+			a int32 `memory:"Root + 0x4"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Compile(&planAddresses{}, tt.data); err == nil {
+			t.Errorf("%s: Compile succeeded", tt.name)
+		}
+	}
+}
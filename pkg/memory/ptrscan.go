@@ -0,0 +1,316 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Defaults of PointerScanOptions.
+const (
+	defaultPointerDepth  = 4
+	defaultPointerOffset = 0x800
+	defaultPointerPaths  = 1000
+
+	// maxPointerFrontier caps the addresses searched per depth, pointers
+	// to popular objects would otherwise make the search explode
+	maxPointerFrontier = 1 << 20
+)
+
+// PointerScanOptions configures ScanPointerPaths. A nil
+// *PointerScanOptions uses the defaults.
+type PointerScanOptions struct {
+	// MaxDepth is the most pointers a path follows, 4 if <= 0.
+	MaxDepth int
+
+	// MaxOffset is the largest offset added to a pointer, 0x800 if <= 0.
+	// Offsets from roots can also be negative, down to -MaxOffset, like
+	// the offsets from signatures in code.
+	MaxOffset int64
+
+	// MaxPaths stops the search once this many paths were found, 1000 if
+	// <= 0.
+	MaxPaths int
+
+	// Filter selects the regions pointers are collected from, writable
+	// regions if nil.
+	Filter Filter
+
+	// Progress is called while collecting pointers, like
+	// ScanOptions.Progress.
+	Progress func(ScanProgress)
+}
+
+// PointerPath is a chain of pointers from a root to an address. The first
+// offset is added to the root, every other one to the pointer read at the
+// address before it:
+//
+//	Root: "Ruleset", Offsets: [0x68, 0x38, 0x92]  =  [[Ruleset + 0x68] + 0x38] + 0x92
+type PointerPath struct {
+	Root    string
+	Offsets []int64
+}
+
+// Depth is the number of pointers the path follows.
+func (pp PointerPath) Depth() int {
+	return len(pp.Offsets) - 1
+}
+
+// String returns the path as a memory expression.
+func (pp PointerPath) String() string {
+	expr := pp.Root + formatOffset(pp.Offsets[0])
+	for _, off := range pp.Offsets[1:] {
+		expr = "[" + expr + "]" + formatOffset(off)
+	}
+
+	return expr
+}
+
+func formatOffset(off int64) string {
+	switch {
+	case off > 0:
+		return fmt.Sprintf(" + 0x%X", off)
+	case off < 0:
+		return fmt.Sprintf(" - 0x%X", -off)
+	default:
+		return ""
+	}
+}
+
+// Resolve follows the path in r starting at the address of its root in
+// roots.
+func (pp PointerPath) Resolve(r io.ReaderAt, roots map[string]int64) (int64, error) {
+	root, ok := roots[pp.Root]
+	if !ok {
+		return 0, fmt.Errorf("undefined root %s", pp.Root)
+	}
+
+	addr := root + pp.Offsets[0]
+	for _, off := range pp.Offsets[1:] {
+		ptr, err := ReadPtr(r, addr, 0)
+		if err != nil {
+			return 0, err
+		}
+
+		addr = ptr + off
+	}
+
+	return addr, nil
+}
+
+// FilterPointerPaths returns the paths that lead to target in r, for
+// narrowing the paths found in one snapshot down with others.
+func FilterPointerPaths(r io.ReaderAt, roots map[string]int64, target int64, paths []PointerPath) []PointerPath {
+	var kept []PointerPath
+
+	for _, pp := range paths {
+		if addr, err := pp.Resolve(r, roots); err == nil && addr == target {
+			kept = append(kept, pp)
+		}
+	}
+
+	return kept
+}
+
+// pointerEntry is a pointer found in memory, value read at addr.
+type pointerEntry struct {
+	value int64
+	addr  int64
+}
+
+// pointerNode is an address the search reached, suffix are the offsets
+// leading from it to the target.
+type pointerNode struct {
+	addr   int64
+	suffix []int64
+}
+
+// ScanPointerPaths finds the pointer paths from roots to target, shortest
+// first. Every pointer in the memory selected by opt is collected once,
+// then the search walks backwards from target: an address is reached by
+// every pointer to at most MaxOffset bytes before it, and is the end of a
+// path if it is within MaxOffset of a root.
+//
+// The result usually holds many paths that only work by chance, check them
+// against other snapshots with FilterPointerPaths.
+func ScanPointerPaths(ctx context.Context, p Process, target int64, roots map[string]int64, opt *PointerScanOptions) ([]PointerPath, error) {
+	if opt == nil {
+		opt = &PointerScanOptions{}
+	}
+
+	depth, maxOffset, maxPaths := opt.MaxDepth, opt.MaxOffset, opt.MaxPaths
+	if depth <= 0 {
+		depth = defaultPointerDepth
+	}
+	if maxOffset <= 0 {
+		maxOffset = defaultPointerOffset
+	}
+	if maxPaths <= 0 {
+		maxPaths = defaultPointerPaths
+	}
+
+	pointers, err := collectPointers(ctx, p, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var paths []PointerPath
+	visited := map[int64]bool{target: true}
+	level := []pointerNode{{addr: target}}
+
+	for d := 0; d <= depth && len(level) > 0; d++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, n := range level {
+			for _, name := range names {
+				if off := n.addr - roots[name]; off >= -maxOffset && off <= maxOffset {
+					paths = append(paths, PointerPath{
+						Root:    name,
+						Offsets: append([]int64{off}, n.suffix...),
+					})
+				}
+			}
+		}
+
+		if len(paths) >= maxPaths || d == depth {
+			break
+		}
+
+		var next []pointerNode
+
+	search:
+		for _, n := range level {
+			i := sort.Search(len(pointers), func(i int) bool {
+				return pointers[i].value >= n.addr-maxOffset
+			})
+
+			for ; i < len(pointers) && pointers[i].value <= n.addr; i++ {
+				ptr := pointers[i]
+				if visited[ptr.addr] {
+					continue
+				}
+
+				visited[ptr.addr] = true
+				next = append(next, pointerNode{
+					addr:   ptr.addr,
+					suffix: append([]int64{n.addr - ptr.value}, n.suffix...),
+				})
+
+				if len(next) >= maxPointerFrontier {
+					break search
+				}
+			}
+		}
+
+		level = next
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		if paths[i].Depth() != paths[j].Depth() {
+			return paths[i].Depth() < paths[j].Depth()
+		}
+
+		return offsetSize(paths[i]) < offsetSize(paths[j])
+	})
+
+	if len(paths) > maxPaths {
+		paths = paths[:maxPaths]
+	}
+
+	return paths, nil
+}
+
+// offsetSize is the sum of the absolute offsets of a path, smaller offsets
+// are more likely to be fields of the same object.
+func offsetSize(pp PointerPath) int64 {
+	var sum int64
+	for _, off := range pp.Offsets {
+		if off < 0 {
+			off = -off
+		}
+		sum += off
+	}
+
+	return sum
+}
+
+// collectPointers reads the regions selected by opt and returns every
+// aligned value pointing into readable memory, sorted by value.
+func collectPointers(ctx context.Context, p Process, opt *PointerScanOptions) ([]pointerEntry, error) {
+	maps, err := p.Maps()
+	if err != nil {
+		return nil, err
+	}
+
+	var readable, sources []Map
+	for _, m := range maps {
+		if !Readable(m) {
+			continue
+		}
+
+		readable = append(readable, m)
+
+		if opt.Filter != nil && opt.Filter(m) || opt.Filter == nil && m.Protection()&ProtWrite != 0 {
+			sources = append(sources, m)
+		}
+	}
+
+	mapped := mappedIn(readable)
+	ptrSize := layoutOf(p).PointerSize
+
+	var progress ScanProgress
+	reporter := progressReporter{callback: opt.Progress}
+	progress.RegionsTotal = len(sources)
+	for _, m := range sources {
+		progress.BytesTotal += m.Size()
+	}
+
+	var pointers []pointerEntry
+	buf := make([]byte, 1<<20)
+
+	for _, m := range sources {
+		for off := int64(0); off < m.Size(); off += int64(len(buf)) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			chunk := buf
+			if rest := m.Size() - off; rest < int64(len(chunk)) {
+				chunk = chunk[:rest]
+			}
+
+			n, _ := readFullAt(p, chunk, m.Start()+off)
+			for i := 0; i+ptrSize <= n; i += ptrSize {
+				if v := int64(bytesToInt(chunk[i : i+ptrSize])); v != 0 && mapped(v) {
+					pointers = append(pointers, pointerEntry{value: v, addr: m.Start() + off + int64(i)})
+				}
+			}
+
+			progress.BytesScanned += int64(len(chunk))
+			reporter.report(progress, false)
+		}
+
+		progress.RegionsDone++
+	}
+
+	reporter.report(progress, true)
+
+	if !isAlive(p) {
+		return nil, ErrProcessExited
+	}
+
+	sort.Slice(pointers, func(i, j int) bool {
+		return pointers[i].value < pointers[j].value
+	})
+
+	return pointers, nil
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type ptrRoots struct {
+	Static int64
+	Other  int64
+}
+
+// newChainProcess maps a chain from the static pointer at 0x400100 over
+// the object at 0x500000 to the target 0x600024:
+//
+//	[[0x400100] + 0x10] + 0x24
+func newChainProcess() *fakeProcess {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x400000, 0x1000).module = `C:\osu!\osu!.exe`
+	p.mapRegion(0x500000, 0x1000)
+	p.mapRegion(0x600000, 0x1000)
+
+	p.putPtr(0x400100, 0x500000)
+	p.putPtr(0x500010, 0x600000)
+
+	return p
+}
+
+func TestScanPointerPaths(t *testing.T) {
+	p := newChainProcess()
+	roots := map[string]int64{"Static": 0x400120, "Other": 0x500000}
+
+	paths, err := ScanPointerPaths(context.Background(), p, 0x600024, roots, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// shortest first, the root is behind the static pointer
+	want := []PointerPath{
+		{Root: "Other", Offsets: []int64{0x10, 0x24}},
+		{Root: "Static", Offsets: []int64{-0x20, 0x10, 0x24}},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("ScanPointerPaths = %v, want %v", paths, want)
+	}
+
+	if s := paths[1].String(); s != "[[Static - 0x20] + 0x10] + 0x24" {
+		t.Errorf("String = %q", s)
+	}
+
+	addrs := &ptrRoots{Static: 0x400120, Other: 0x500000}
+	for _, pp := range paths {
+		if addr, err := Evaluate(p, addrs, pp.String()); err != nil || addr != 0x600024 {
+			t.Errorf("Evaluate(%q) = 0x%x, %v, want the target", pp, addr, err)
+		}
+
+		if addr, err := pp.Resolve(p, roots); err != nil || addr != 0x600024 {
+			t.Errorf("%v.Resolve = 0x%x, %v, want the target", pp, addr, err)
+		}
+	}
+
+	if paths, err := ScanPointerPaths(context.Background(), p, 0x600024, roots, &PointerScanOptions{MaxDepth: 1}); err != nil || len(paths) != 1 {
+		t.Errorf("ScanPointerPaths one deep = %v, %v, want the path from Other", paths, err)
+	}
+
+	// in the next session the static pointer refers to another object
+	next := newChainProcess()
+	next.mapRegion(0x700000, 0x1000)
+	next.putPtr(0x400100, 0x700000)
+
+	if kept := FilterPointerPaths(next, roots, 0x600024, paths); !reflect.DeepEqual(kept, want[:1]) {
+		t.Errorf("FilterPointerPaths = %v, want %v", kept, want[:1])
+	}
+
+	if _, err := (PointerPath{Root: "Missing", Offsets: []int64{0}}).Resolve(p, roots); err == nil {
+		t.Error("Resolve from an undefined root succeeded")
+	}
+}
+
+func TestCollectPointers(t *testing.T) {
+	p := newChainProcess()
+	p.regions[0].prot = ProtRead
+	// unaligned, null and dangling values aren't pointers
+	p.putPtr(0x500022, 0x600000)
+	p.putPtr(0x500030, 0x900000)
+
+	pointers, err := collectPointers(context.Background(), p, &PointerScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only writable regions by default
+	if want := []pointerEntry{{value: 0x600000, addr: 0x500010}}; !reflect.DeepEqual(pointers, want) {
+		t.Errorf("collectPointers = %+v, want %+v", pointers, want)
+	}
+
+	var progress ScanProgress
+	pointers, err = collectPointers(context.Background(), p, &PointerScanOptions{
+		Filter:   func(m Map) bool { return m.Start() == 0x400000 },
+		Progress: func(sp ScanProgress) { progress = sp },
+	})
+
+	if want := []pointerEntry{{value: 0x500000, addr: 0x400100}}; err != nil || !reflect.DeepEqual(pointers, want) {
+		t.Errorf("collectPointers of the image = %+v, %v, want %+v", pointers, err, want)
+	}
+
+	if progress.BytesScanned != 0x1000 || progress.RegionsDone != 1 || progress.RegionsTotal != 1 {
+		t.Errorf("final progress %+v", progress)
+	}
+
+	p.exited = true
+	if _, err := collectPointers(context.Background(), p, &PointerScanOptions{}); err == nil {
+		t.Error("collectPointers after exit succeeded")
+	}
+}
+
+func TestScanPointerPathsFrontier(t *testing.T) {
+	if testing.Short() {
+		t.Skip("maps millions of pointers")
+	}
+
+	const (
+		extra  = 16
+		slots  = maxPointerFrontier + extra
+		target = 0x10480000
+		table  = 0x20000000
+	)
+
+	// slot k of the table points k * 4 bytes in front of target, slot 0
+	// at the end of the table. The search only takes the slots with the
+	// lowest values, so the last extra slots are never reached.
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x10000000, 0x500000)
+	p.mapRegion(table, 4*slots)
+	p.mapRegion(0x30000000, 0x1000)
+	p.mapRegion(0x31000000, 0x1000)
+
+	data := make([]byte, 4*slots)
+	for k := 0; k < slots; k++ {
+		binary.LittleEndian.PutUint32(data[4*(slots-1-k):], uint32(target-4*k))
+	}
+	p.write(table, data)
+
+	// Kept points to the start of the table, Dropped to its end
+	p.putPtr(0x30000000, table)
+	p.putPtr(0x31000000, table+4*(slots-1))
+
+	roots := map[string]int64{"Kept": 0x30000000, "Dropped": 0x31000000}
+	opt := &PointerScanOptions{MaxDepth: 2, MaxOffset: 0x800000}
+
+	paths, err := ScanPointerPaths(context.Background(), p, target, roots, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PointerPath{{Root: "Kept", Offsets: []int64{0, 0, 4 * (slots - 1)}}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("ScanPointerPaths = %v, want %v", paths, want)
+	}
+}
@@ -58,10 +58,10 @@ func removeLast(slice []int64) ([]int64, *int64) {
 func followOffsets(r io.ReaderAt, addr int64, offsets ...int64) (int64, error) {
 	start, last := removeLast(offsets)
 
-	for _, offset := range start {
+	for hop, offset := range start {
 		newaddr, err := ReadPtr(r, addr+offset, 0)
 		if err != nil {
-			return 0, err
+			return 0, pointerError(err, addr+offset, hop)
 		}
 		addr = newaddr
 	}
@@ -89,7 +89,17 @@ func readUint(r io.ReaderAt, addr int64, size int, offsets ...int64) (uint64, er
 		return 0, err
 	}
 
-	return readUintRaw(r, addr, size)
+	v, err := readUintRaw(r, addr, size)
+	if err != nil {
+		hop := len(offsets) - 1
+		if hop < 0 {
+			hop = 0
+		}
+
+		return 0, pointerError(err, addr, hop)
+	}
+
+	return v, nil
 }
 
 func readUintArray(r io.ReaderAt, addr int64, size int,
@@ -99,7 +109,9 @@ func readUintArray(r io.ReaderAt, addr int64, size int,
 		return nil, err
 	}
 
-	length, err := ReadInt32(r, base, 12)
+	layout := layoutOf(r)
+
+	length, err := ReadInt32(r, base, layout.ListSize)
 	if err != nil {
 		return nil, err
 	}
@@ -112,13 +124,13 @@ func readUintArray(r io.ReaderAt, addr int64, size int,
 		return nil, ErrArrayTooLong
 	}
 
-	data, err := ReadPtr(r, base, 4)
+	data, err := ReadPtr(r, base, layout.ListItems)
 	if err != nil {
 		return nil, err
 	}
 
 	buf := make([]byte, int(length)*size)
-	_, err = readFullAt(r, buf, data+8)
+	_, err = readFullAt(r, buf, data+layout.ArrayData)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +149,9 @@ func ReadString(r io.ReaderAt, addr int64, offsets ...int64) (string, error) {
 		return "", err
 	}
 
-	length, err := ReadUint32(r, base, 4)
+	layout := layoutOf(r)
+
+	length, err := ReadUint32(r, base, layout.StringLength)
 	if err != nil {
 		return "", err
 	}
@@ -152,7 +166,7 @@ func ReadString(r io.ReaderAt, addr int64, offsets ...int64) (string, error) {
 
 	buf := make([]byte, length*2)
 
-	_, err = readFullAt(r, buf, base+8)
+	_, err = readFullAt(r, buf, base+layout.StringData)
 	if err != nil {
 		return "", err
 	}
@@ -165,6 +179,11 @@ func ReadString(r io.ReaderAt, addr int64, offsets ...int64) (string, error) {
 	return string(utf16.Decode(buf16)), nil
 }
 
+func ReadBool(r io.ReaderAt, addr int64, offsets ...int64) (bool, error) {
+	num, err := readUint(r, addr, 1, offsets...)
+	return num != 0, err
+}
+
 func ReadInt8(r io.ReaderAt, addr int64, offsets ...int64) (int8, error) {
 	num, err := readUint(r, addr, 1, offsets...)
 	return int8(int64(num)), err
@@ -305,7 +324,9 @@ func ReadFloat64Array(r io.ReaderAt, addr int64, offsets ...int64) ([]float64, e
 	return array64, err
 }
 
+// ReadPtr reads a pointer of the size given by the layout of r, see
+// Layout.
 func ReadPtr(r io.ReaderAt, addr int64, offsets ...int64) (int64, error) {
-	num, err := ReadUint32(r, addr, offsets...)
+	num, err := readUint(r, addr, layoutOf(r).PointerSize, offsets...)
 	return int64(num), err
 }
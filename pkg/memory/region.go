@@ -0,0 +1,291 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Protection is the access allowed to the pages of a region.
+type Protection uint8
+
+const (
+	ProtRead Protection = 1 << iota
+	ProtWrite
+	ProtExec
+	// ProtGuard pages fault on the first access (Windows only)
+	ProtGuard
+)
+
+func (p Protection) String() string {
+	b := []byte("---")
+
+	if p&ProtRead != 0 {
+		b[0] = 'r'
+	}
+	if p&ProtWrite != 0 {
+		b[1] = 'w'
+	}
+	if p&ProtExec != 0 {
+		b[2] = 'x'
+	}
+	if p&ProtGuard != 0 {
+		b = append(b, 'g')
+	}
+
+	return string(b)
+}
+
+// RegionState tells whether a region is backed by memory.
+type RegionState uint8
+
+const (
+	StateCommitted RegionState = iota
+	StateReserved
+	StateFree
+)
+
+func (s RegionState) String() string {
+	switch s {
+	case StateCommitted:
+		return "committed"
+	case StateReserved:
+		return "reserved"
+	case StateFree:
+		return "free"
+	default:
+		return "unknown"
+	}
+}
+
+// RegionType tells what a region was allocated for.
+type RegionType uint8
+
+const (
+	// TypePrivate is anonymous memory, the heap and JIT code live here
+	TypePrivate RegionType = iota
+	// TypeImage is a mapped executable or library
+	TypeImage
+	// TypeMapped is any other mapped file
+	TypeMapped
+)
+
+func (t RegionType) String() string {
+	switch t {
+	case TypePrivate:
+		return "private"
+	case TypeImage:
+		return "image"
+	case TypeMapped:
+		return "mapped"
+	default:
+		return "unknown"
+	}
+}
+
+// Windows PAGE_*, MEM_* constants as reported by VirtualQueryEx and the
+// minidump MemoryInfoListStream.
+const (
+	pageNoAccess         = 0x01
+	pageReadOnly         = 0x02
+	pageReadWrite        = 0x04
+	pageWriteCopy        = 0x08
+	pageExecute          = 0x10
+	pageExecuteRead      = 0x20
+	pageExecuteReadWrite = 0x40
+	pageExecuteWriteCopy = 0x80
+	pageGuard            = 0x100
+
+	memCommit  = 0x1000
+	memReserve = 0x2000
+	memFree    = 0x10000
+
+	memPrivate = 0x20000
+	memMapped  = 0x40000
+	memImage   = 0x1000000
+)
+
+func windowsProtection(protect uint32) Protection {
+	var p Protection
+
+	switch protect & 0xFF {
+	case pageReadOnly:
+		p = ProtRead
+	case pageReadWrite, pageWriteCopy:
+		p = ProtRead | ProtWrite
+	case pageExecute:
+		p = ProtExec
+	case pageExecuteRead:
+		p = ProtRead | ProtExec
+	case pageExecuteReadWrite, pageExecuteWriteCopy:
+		p = ProtRead | ProtWrite | ProtExec
+	}
+
+	if protect&pageGuard != 0 {
+		p |= ProtGuard
+	}
+
+	return p
+}
+
+func windowsState(state uint32) RegionState {
+	switch state {
+	case memCommit:
+		return StateCommitted
+	case memReserve:
+		return StateReserved
+	default:
+		return StateFree
+	}
+}
+
+func windowsType(type_ uint32) RegionType {
+	switch type_ {
+	case memImage:
+		return TypeImage
+	case memMapped:
+		return TypeMapped
+	default:
+		return TypePrivate
+	}
+}
+
+// moduleName returns the file name of a module path, Windows and device
+// paths included.
+func moduleName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+
+	return path
+}
+
+// Filter selects the regions a scan reads.
+type Filter func(Map) bool
+
+// Readable selects committed regions that can be read without faulting.
+// Scans always apply it on top of their own filter.
+func Readable(m Map) bool {
+	return m.State() == StateCommitted &&
+		m.Protection()&ProtRead != 0 &&
+		m.Protection()&ProtGuard == 0
+}
+
+// Executable selects readable code regions of images and the JIT.
+func Executable(m Map) bool {
+	return Readable(m) && m.Protection()&ProtExec != 0
+}
+
+// JIT selects executable private regions, where the .NET runtime puts
+// compiled methods.
+func JIT(m Map) bool {
+	return Executable(m) && m.Type() == TypePrivate
+}
+
+// Heap selects private read-write data regions.
+func Heap(m Map) bool {
+	return Readable(m) &&
+		m.Type() == TypePrivate &&
+		m.Protection()&(ProtWrite|ProtExec) == ProtWrite
+}
+
+// InModule selects the regions mapped from a module with the given file
+// name, for example "osu!.exe". Case is ignored.
+func InModule(name string) Filter {
+	return func(m Map) bool {
+		return strings.EqualFold(moduleName(m.Module()), name)
+	}
+}
+
+// ModuleBase returns the lowest address mapped from the module with the
+// given file name, for example "osu!.exe". Case is ignored.
+func ModuleBase(p interface{ Maps() ([]Map, error) }, name string) (int64, error) {
+	maps, err := p.Maps()
+	if err != nil {
+		return 0, err
+	}
+
+	base, found := int64(0), false
+	for _, m := range maps {
+		if InModule(name)(m) && (!found || m.Start() < base) {
+			base, found = m.Start(), true
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("%w: %s", ErrModuleNotFound, name)
+	}
+
+	return base, nil
+}
+
+// And selects the regions all filters select.
+func And(filters ...Filter) Filter {
+	return func(m Map) bool {
+		for _, f := range filters {
+			if !f(m) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or selects the regions any of the filters selects.
+func Or(filters ...Filter) Filter {
+	return func(m Map) bool {
+		for _, f := range filters {
+			if f(m) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// mapInfo is a Map holding a copy of every attribute, it is used by the
+// processes that are read from files.
+type mapInfo struct {
+	start  int64
+	size   int64
+	prot   Protection
+	state  RegionState
+	type_  RegionType
+	module string
+}
+
+func copyMap(m Map) mapInfo {
+	return mapInfo{
+		start:  m.Start(),
+		size:   m.Size(),
+		prot:   m.Protection(),
+		state:  m.State(),
+		type_:  m.Type(),
+		module: m.Module(),
+	}
+}
+
+func (m mapInfo) Start() int64 {
+	return m.start
+}
+
+func (m mapInfo) Size() int64 {
+	return m.size
+}
+
+func (m mapInfo) Protection() Protection {
+	return m.prot
+}
+
+func (m mapInfo) State() RegionState {
+	return m.state
+}
+
+func (m mapInfo) Type() RegionType {
+	return m.type_
+}
+
+func (m mapInfo) Module() string {
+	return m.module
+}
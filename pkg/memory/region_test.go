@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newRegionProcess maps one region of every kind with "AB CD EF" at its
+// start.
+func newRegionProcess() *fakeProcess {
+	p := newFakeProcess(Layout{})
+
+	regions := []struct {
+		start  int64
+		prot   Protection
+		state  RegionState
+		type_  RegionType
+		module string
+	}{
+		{0x10000, ProtRead, StateCommitted, TypeImage, `\Device\HarddiskVolume3\osu!\osu!.exe`},
+		{0x11000, ProtRead | ProtExec, StateCommitted, TypeImage, `C:\osu!\osu!.exe`},
+		{0x20000, ProtRead | ProtExec, StateCommitted, TypeImage, `C:\Windows\System32\ntdll.dll`},
+		{0x30000, ProtRead | ProtWrite | ProtExec, StateCommitted, TypePrivate, ""},
+		{0x40000, ProtRead | ProtWrite, StateCommitted, TypePrivate, ""},
+		{0x50000, ProtRead | ProtWrite | ProtGuard, StateCommitted, TypePrivate, ""},
+		{0x60000, ProtRead | ProtWrite, StateReserved, TypePrivate, ""},
+		{0x70000, ProtRead, StateCommitted, TypeMapped, "/usr/share/fonts/font.ttf"},
+		{0x80000, 0, StateCommitted, TypePrivate, ""},
+	}
+
+	for _, r := range regions {
+		reg := p.mapRegion(r.start, 0x1000)
+		reg.prot, reg.state, reg.type_, reg.module = r.prot, r.state, r.type_, r.module
+		copy(reg.data, []byte{0xAB, 0xCD, 0xEF})
+	}
+
+	return p
+}
+
+func TestFilters(t *testing.T) {
+	p := newRegionProcess()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"Readable", Readable, []int64{0x10000, 0x11000, 0x20000, 0x30000, 0x40000, 0x70000}},
+		{"Executable", Executable, []int64{0x11000, 0x20000, 0x30000}},
+		{"JIT", JIT, []int64{0x30000}},
+		{"Heap", Heap, []int64{0x40000}},
+		{"InModule", InModule("OSU!.exe"), []int64{0x10000, 0x11000}},
+		{"InModule of a file", InModule("font.ttf"), []int64{0x70000}},
+		{"And", And(Executable, InModule("osu!.exe")), []int64{0x11000}},
+		{"Or", Or(JIT, Heap), []int64{0x30000, 0x40000}},
+	}
+
+	maps, _ := p.Maps()
+
+	for _, tt := range tests {
+		var got []int64
+		for _, m := range maps {
+			if tt.filter(m) {
+				got = append(got, m.Start())
+			}
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s selects %x, want %x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanFilter(t *testing.T) {
+	p := newRegionProcess()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		// regions that can't be read are skipped without a filter too
+		{"none", nil, []int64{0x10000, 0x11000, 0x20000, 0x30000, 0x40000, 0x70000}},
+		{"JIT or osu!.exe", Or(JIT, InModule("osu!.exe")), []int64{0x10000, 0x11000, 0x30000}},
+		{"everything", func(Map) bool { return true }, []int64{0x10000, 0x11000, 0x20000, 0x30000, 0x40000, 0x70000}},
+	}
+
+	for _, tt := range tests {
+		for _, workers := range []int{1, 4} {
+			matches, err := ScanAllContext(context.Background(), p, "AB CD EF", &ScanOptions{Filter: tt.filter, Workers: workers})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int64
+			for _, m := range matches {
+				got = append(got, m.Address)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s, %d workers: matches at %x, want %x", tt.name, workers, got, tt.want)
+			}
+		}
+
+		addr, err := ScanContext(context.Background(), p, "AB CD EF", &ScanOptions{Filter: tt.filter})
+		if err != nil || addr != tt.want[0] {
+			t.Errorf("%s: Scan = 0x%x, %v, want 0x%x", tt.name, addr, err, tt.want[0])
+		}
+	}
+}
+
+func TestModuleBase(t *testing.T) {
+	p := newRegionProcess()
+
+	tests := []struct {
+		name string
+		want int64
+	}{
+		{"osu!.exe", 0x10000},
+		{"NTDLL.DLL", 0x20000},
+		{"font.ttf", 0x70000},
+	}
+
+	for _, tt := range tests {
+		if base, err := ModuleBase(p, tt.name); err != nil || base != tt.want {
+			t.Errorf("ModuleBase(%q) = 0x%x, %v, want 0x%x", tt.name, base, err, tt.want)
+		}
+	}
+
+	if _, err := ModuleBase(p, "osu!"); !errors.Is(err, ErrModuleNotFound) {
+		t.Errorf("ModuleBase(osu!) = %v, want ErrModuleNotFound", err)
+	}
+
+	p.exited = true
+	if _, err := ModuleBase(p, "osu!.exe"); !errors.Is(err, ErrProcessExited) {
+		t.Errorf("ModuleBase after exit = %v, want ErrProcessExited", err)
+	}
+}
@@ -1,162 +1,250 @@
 package memory
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
-	"strconv"
-	"strings"
-	"text/scanner"
 )
 
-type pattern struct {
-	Bytes []uint32
-	Mask  []uint32
+// ScanOptions configures a scan. A nil *ScanOptions uses the defaults.
+type ScanOptions struct {
+	// Workers is the number of goroutines reading regions in parallel,
+	// runtime.NumCPU() if <= 0. A single worker reads the regions in order
+	// and the same ones every time, which replaying a trace needs.
+	Workers int
+
+	// Progress is called from the scanning goroutines every few hundred
+	// milliseconds and once when the scan ends.
+	Progress func(ScanProgress)
+
+	// Filter limits the scan to the regions it selects. Regions that can't
+	// be read are always skipped.
+	Filter Filter
+
+	// Cache is a file ResolvePatterns remembers resolved signatures in. When
+	// the same process is seen again the cached addresses are checked
+	// against their patterns and only the ones that changed are scanned.
+	Cache string
+
+	// Strict makes ResolvePatterns look for every match of every signature
+	// instead of the first one, see StrictMode. Cached addresses are not
+	// used, uniqueness can only be checked by scanning.
+	Strict StrictMode
+
+	// Report is called by ResolvePatterns once for every sig field after
+	// it was resolved or failed to resolve.
+	Report func(SignatureReport)
+
+	// Signatures replace the sig tags of the int64 fields with the same
+	// names when resolving, fields without a tag get one. Usually the
+	// Signatures of Definitions. Names of no field are ignored, see
+	// Definitions.CheckSignatures.
+	Signatures map[string]string
 }
 
-func parsePattern(s string) (pattern, error) {
-	var bytess, mask []byte
+// StrictMode is what ResolvePatterns does about signatures that match more
+// than one address.
+type StrictMode uint8
+
+const (
+	// StrictOff uses the first match without looking for others
+	StrictOff StrictMode = iota
+	// StrictWarn uses the first match, ambiguous signatures only show up in
+	// the reports
+	StrictWarn
+	// StrictFail skips ambiguous alternatives, fields without an
+	// unambiguous one are left unset and fail with ErrAmbiguousPattern
+	StrictFail
+)
 
-	for _, str := range strings.Split(s, " ") {
-		if str == "??" {
-			bytess = append(bytess, 0x00)
-			mask = append(mask, 0x00)
-			continue
-		}
+// Match is a place a pattern matched.
+type Match struct {
+	// Address is the start of the match
+	Address int64
+	// Resolved is the address the pattern stands for, Address unless the
+	// pattern has a capture
+	Resolved int64
+	Region   Map
+}
 
-		b, err := strconv.ParseUint(str, 16, 8)
-		if err != nil {
-			return pattern{}, err
-		}
+// SignatureReport describes how a sig field was resolved.
+type SignatureReport struct {
+	Field     string
+	Signature string
+	// Alternative is the index of the alternative of Signature that
+	// matched, Pattern its text. Alternative is -1 if none matched.
+	Alternative int
+	Pattern     string
+	// Matches are every match of Pattern in strict mode, otherwise only
+	// the one that was used. Matches found without strict mode or taken
+	// from the cache have no Region.
+	Matches []Match
+	// Err is why the field could not be resolved, nil if it was
+	Err error
+}
 
-		bytess = append(bytess, byte(b))
-		mask = append(mask, 0xFF)
+// Ambiguous reports whether the matches resolve to more than one address.
+// Matches resolving to the same address, like two instructions referencing
+// the same global, are not ambiguous.
+func (r SignatureReport) Ambiguous() bool {
+	for _, m := range r.Matches {
+		if m.Resolved != r.Matches[0].Resolved {
+			return true
+		}
 	}
 
-	var p pattern
-	for i := 0; i < len(bytess); i += 4 {
-		if i+4 > len(bytess) {
-			break
-		}
+	return false
+}
 
-		var byt, mas uint32
+// Regions returns the distinct regions the matches are in.
+func (r SignatureReport) Regions() []Map {
+	var regions []Map
 
-		for j := 0; j < 4; j++ {
-			byt |= uint32(bytess[i+j]) << (j * 8)
-			mas |= uint32(mask[i+j]) << (j * 8)
+	for _, m := range r.Matches {
+		if m.Region == nil {
+			continue
 		}
 
-		p.Bytes = append(p.Bytes, byt)
-		p.Mask = append(p.Mask, mas)
+		if len(regions) == 0 || m.Region.Start() != regions[len(regions)-1].Start() {
+			regions = append(regions, m.Region)
+		}
 	}
 
-	return p, nil
+	return regions
 }
 
-func maskByte(needle, mask uint32) (search byte, offset int) {
-	for offset := 0; offset < 4; offset++ {
-		maskByte := byte(mask >> (offset * 8) & 0xFF)
-		needleByte := byte(needle >> (offset * 8) & 0xFF)
-		if maskByte != 0x00 && needleByte != 0x00 {
-			return needleByte, offset
-		}
+// ScanProgress is a point in time view of a running scan.
+type ScanProgress struct {
+	BytesScanned int64
+	BytesTotal   int64
+	RegionsDone  int
+	RegionsTotal int
+
+	// Found is the number of patterns out of Total that matched so far
+	Found int
+	Total int
+}
+
+func scanOptions(opts []*ScanOptions) *ScanOptions {
+	if len(opts) > 0 && opts[0] != nil {
+		return opts[0]
 	}
 
-	panic("empty mask (bad pattern)")
+	return &ScanOptions{}
 }
 
-func search(buf []byte, pat pattern) (int, bool) {
-	progress := 0
-	maskByte, offset := maskByte(pat.Bytes[0], pat.Mask[0])
+func Scan(p Process, pattern string) (int64, error) {
+	return ScanContext(context.Background(), p, pattern)
+}
 
-	for {
-		i := bytes.IndexByte(buf, maskByte)
-		if i == -1 {
-			return 0, false
-		}
+// ScanContext is like Scan but can be cancelled through ctx.
+func ScanContext(ctx context.Context, p Process, pattern string, opts ...*ScanOptions) (int64, error) {
+	addrs, err := ScanManyContext(ctx, p, []string{pattern}, opts...)
+	if err != nil {
+		return 0, err
+	}
 
-		begin := i - offset
-		end := begin + len(pat.Bytes)*4
-		if begin < 0 || end > len(buf) {
-			progress += i + 1
-			buf = buf[i+1:]
-			continue
-		}
+	return addrs[0], nil
+}
 
-		success := true
+// ScanAll finds every match of signature, sorted by address. No match is not
+// an error, the result is just empty.
+func ScanAll(p Process, signature string) ([]Match, error) {
+	return ScanAllContext(context.Background(), p, signature)
+}
 
-		for j := range pat.Bytes {
-			needle, mask := pat.Bytes[j], pat.Mask[j]
-			slice := buf[begin+(j*4) : begin+(j*4)+4]
-			haystack := binary.LittleEndian.Uint32(slice)
-			if needle^haystack&mask != 0 {
-				success = false
-				break
-			}
-		}
+// ScanAllContext is like ScanAll but can be cancelled through ctx.
+func ScanAllContext(ctx context.Context, p Process, signature string, opts ...*ScanOptions) ([]Match, error) {
+	pat, err := parsePattern(signature)
+	if err != nil {
+		return nil, err
+	}
 
-		if success {
-			return begin + progress, true
-		}
+	s, err := newMultiScanner([]pattern{pat})
+	if err != nil {
+		return nil, err
+	}
 
-		progress += i + 1
-		buf = buf[i+1:]
+	matches, err := s.scanAll(ctx, p, scanOptions(opts))
+	if err != nil {
+		return nil, err
 	}
-}
 
-func find(p Process, pat pattern, reg Map) (int64, error) {
-	const bufSize = 65536
-	var buf [bufSize]byte
+	if err := resolveMatches(p, pat, matches[0]); err != nil {
+		return nil, err
+	}
 
-	for i := int64(0); i < reg.Size(); {
-		nToRead := reg.Size() - i
-		if nToRead >= bufSize {
-			nToRead = bufSize - 1
-		}
+	return matches[0], nil
+}
 
-		n, err := p.ReadAt(buf[:nToRead], reg.Start()+i)
+// resolveMatches fills in Match.Resolved.
+func resolveMatches(r io.ReaderAt, pat pattern, matches []Match) error {
+	for i := range matches {
+		addr, err := pat.resolve(r, matches[i].Address)
 		if err != nil {
-			return 0, err
+			return fmt.Errorf("resolving capture of %s at 0x%x: %w", pat, matches[i].Address, err)
 		}
+		matches[i].Resolved = addr
+	}
 
-		if at, ok := search(buf[:n], pat); ok {
-			return reg.Start() + i + int64(at), nil
-		}
+	return nil
+}
 
-		diff := n - len(pat.Bytes)*8
-		if diff <= 1 {
-			diff = 1
-		}
+// ScanMany finds the first match of every pattern while reading the memory
+// of p only once. Patterns that were not found are left at 0 and reported in
+// the returned error.
+func ScanMany(p Process, patterns ...string) ([]int64, error) {
+	return ScanManyContext(context.Background(), p, patterns)
+}
 
-		i += int64(diff)
+// ScanManyContext is like ScanMany but can be cancelled through ctx.
+func ScanManyContext(ctx context.Context, p Process, patterns []string, opts ...*ScanOptions) ([]int64, error) {
+	pats := make([]pattern, len(patterns))
+	for i, str := range patterns {
+		pat, err := parsePattern(str)
+		if err != nil {
+			return nil, err
+		}
+		pats[i] = pat
 	}
 
-	return 0, ErrPatternNotFound
-}
-
-func Scan(p Process, pattern string) (int64, error) {
-	maps, err := p.Maps()
+	s, err := newMultiScanner(pats)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	pat, err := parsePattern(pattern)
+	addrs, ok, err := s.scan(ctx, p, scanOptions(opts))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	for _, reg := range maps {
-		if i, err := find(p, pat, reg); err == nil {
-			return i, nil
+	var errs []error
+	for i := range ok {
+		if !ok[i] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrPatternNotFound, patterns[i]))
+			continue
+		}
+
+		if addrs[i], err = pats[i].resolve(p, addrs[i]); err != nil {
+			errs = append(errs, fmt.Errorf("resolving capture of %s: %w", patterns[i], err))
 		}
 	}
 
-	return 0, fmt.Errorf("no internal matched the pattern: %s", pattern)
+	return addrs, errors.Join(errs...)
 }
 
+// ResolvePatterns sets the int64 fields of offsets tagged `sig:"..."` to
+// the address their signature resolves to. A sig tag can list alternatives
+// separated by "|", the first one that matches and resolves is used.
 func ResolvePatterns(p Process, offsets interface{}) error {
+	return ResolvePatternsContext(context.Background(), p, offsets)
+}
+
+// ResolvePatternsContext is like ResolvePatterns but can be cancelled
+// through ctx.
+func ResolvePatternsContext(ctx context.Context, p Process, offsets interface{}, opts ...*ScanOptions) error {
 	pVal := reflect.ValueOf(offsets)
 	val := reflect.Indirect(pVal)
 	valType := val.Type()
@@ -165,110 +253,220 @@ func ResolvePatterns(p Process, offsets interface{}) error {
 		panic("offsets must be a pointer to a struct")
 	}
 
-	var anyErr error
+	opt := scanOptions(opts)
+
+	var cache *signatureCache
+	var id processIdentity
+	var cached map[string]int64
+
+	if opt.Cache != "" {
+		cache = loadCache(opt.Cache)
+		id = identify(p)
+		cached = cache.lookup(id)
+	}
+
+	var errs []error
+	var fields []sigField
+	var pats []pattern
 
+	resolved := map[string]int64{}
+
+	// set resolves field to its alternative alt, reports it and remembers
+	// it for the cache
+	set := func(f sigField, alt int, matches []Match) {
+		val.Field(f.index).Set(reflect.ValueOf(matches[0].Resolved))
+		resolved[f.alts[alt].String()] = matches[0].Address
+
+		f.report(opt, SignatureReport{Alternative: alt, Pattern: f.alts[alt].String(), Matches: matches})
+	}
+
+	fail := func(f sigField, report SignatureReport) {
+		errs = append(errs, report.Err)
+		f.report(opt, report)
+	}
+
+fieldLoop:
 	for i := 0; i < val.NumField(); i++ {
 		field := valType.Field(i)
 
 		sig, ok := field.Tag.Lookup("sig")
+		if defined, found := opt.Signatures[field.Name]; found && field.Type.Kind() == reflect.Int64 {
+			sig, ok = defined, true
+		}
+
 		if !ok {
 			continue
 		}
 
-		offset, err := Scan(p, sig)
+		alts, err := parseSignature(sig)
 		if err != nil {
-			anyErr = err
+			errs = append(errs, err)
 			continue
 		}
 
-		val.Field(i).Set(reflect.ValueOf(offset))
-	}
+		f := sigField{index: i, name: field.Name, sig: sig, alts: alts, first: len(pats)}
 
-	return anyErr
-}
+		for j, alt := range alts {
+			match, ok := cached[alt.String()]
+			if !ok || opt.Strict != StrictOff || !verify(p, alt, match) {
+				continue
+			}
 
-func Read(r io.ReaderAt, addresses interface{}, p interface{}) error {
-	addrPVal := reflect.ValueOf(addresses)
-	addrVal := addrPVal.Elem()
+			if addr, err := alt.resolve(p, match); err == nil {
+				set(f, j, []Match{{Address: match, Resolved: addr}})
+				continue fieldLoop
+			}
+		}
 
-	if addrVal.Kind() != reflect.Struct {
-		panic("addresses must be a pointer to a struct")
+		fields = append(fields, f)
+		pats = append(pats, alts...)
 	}
 
-	pVal := reflect.ValueOf(p)
-	val := pVal.Elem()
-	valueType := val.Type()
+	if len(pats) > 0 && opt.Strict != StrictOff {
+		s, err := newMultiScanner(pats)
+		if err != nil {
+			return err
+		}
 
-	if val.Kind() != reflect.Struct {
-		panic("p must be a pointer to a struct")
-	}
+		all, err := s.scanAll(ctx, p, opt)
+		if err != nil {
+			return err
+		}
 
-	var errs ReadError
+	strictLoop:
+		for _, f := range fields {
+			var failed []SignatureReport
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldT := valueType.Field(i)
+			for j, alt := range f.alts {
+				matches := all[f.first+j]
+				if len(matches) == 0 {
+					continue
+				}
 
-		tag, ok := fieldT.Tag.Lookup("memory")
-		if !ok {
-			continue
+				report := SignatureReport{Alternative: j, Pattern: alt.String(), Matches: matches}
+
+				switch err := resolveMatches(p, alt, matches); {
+				case err != nil:
+					report.Err = err
+				case report.Ambiguous() && opt.Strict == StrictFail:
+					report.Err = fmt.Errorf("%w: %s matched %d times", ErrAmbiguousPattern, alt, len(matches))
+				default:
+					set(f, j, matches)
+					continue strictLoop
+				}
+
+				failed = append(failed, report)
+			}
+
+			fail(f, failedReport(f, failed))
+		}
+	} else if len(pats) > 0 {
+		s, err := newMultiScanner(pats)
+		if err != nil {
+			return err
 		}
 
-		evalFunc := func(addr int64) (int64, error) {
-			return ReadPtr(r, addr, 0)
+		addrs, ok, err := s.scan(ctx, p, opt)
+		if err != nil {
+			return err
 		}
 
-		var varFunc func(name string) (int64, error)
+	firstLoop:
+		for _, f := range fields {
+			var failed []SignatureReport
 
-		varFunc = func(name string) (int64, error) {
-			field := addrVal.FieldByName(name)
-			if field.IsValid() {
-				return field.Interface().(int64), nil
-			}
+			for j, alt := range f.alts {
+				if !ok[f.first+j] {
+					continue
+				}
 
-			method := addrVal.Addr().MethodByName(name)
-			if method.IsValid() {
-				ret := method.Call([]reflect.Value{})
-				exprStr := ret[0].Interface().(string)
+				match := addrs[f.first+j]
 
-				expr, err := parseMem(exprStr, varFunc)
+				addr, err := alt.resolve(p, match)
 				if err != nil {
-					return 0, err
+					failed = append(failed, SignatureReport{
+						Alternative: j,
+						Pattern:     alt.String(),
+						Err:         fmt.Errorf("resolving capture of %s: %w", alt, err),
+					})
+					continue
 				}
 
-				return expr.eval(evalFunc)
+				set(f, j, []Match{{Address: match, Resolved: addr}})
+				continue firstLoop
 			}
 
-			return 0, fmt.Errorf("undefined variable %s", name)
+			fail(f, failedReport(f, failed))
 		}
+	}
 
-		expr, err := parseMem(tag, varFunc)
-		if err != nil {
-			return fmt.Errorf("failed to parse memory tag for %s.%s: %w", valueType.Name(), fieldT.Name, err)
+	if cache != nil && len(resolved) > 0 {
+		cache.store(id, resolved)
+		if err := cache.save(opt.Cache); err != nil {
+			errs = append(errs, fmt.Errorf("saving signature cache: %w", err))
 		}
+	}
 
-		addr, err := expr.eval(evalFunc)
-		if err != nil {
-			return fmt.Errorf("failed to read %s.%s: %w", valueType.Name(), fieldT.Name, err)
-		}
+	return errors.Join(errs...)
+}
 
-		if err := readPrimitive(r, field.Addr().Interface(), addr, 0); err != nil {
-			err = fmt.Errorf("failed to read %s.%s: %w", valueType.Name(), fieldT.Name, err)
-			errs = append(errs, err)
-		}
+// sigField is a field ResolvePatterns resolves, the patterns of its
+// alternatives are scanned from index first on.
+type sigField struct {
+	index int
+	name  string
+	sig   string
+	alts  []pattern
+	first int
+}
+
+// failedReport is the report of f when none of its alternatives could be
+// used. It describes the first alternative that matched but failed, with
+// the errors of all of them, or that none matched at all.
+func failedReport(f sigField, failed []SignatureReport) SignatureReport {
+	if len(failed) == 0 {
+		return SignatureReport{Alternative: -1, Err: fmt.Errorf("%w: %s", ErrPatternNotFound, f.sig)}
 	}
 
-	if len(errs) != 0 {
-		return errs
+	errs := make([]error, len(failed))
+	for i, r := range failed {
+		errs[i] = r.Err
 	}
 
-	return nil
+	report := failed[0]
+	report.Err = errors.Join(errs...)
+	return report
+}
+
+// report fills in the field and signature of r and hands it to
+// opt.Report.
+func (f sigField) report(opt *ScanOptions, r SignatureReport) {
+	if opt.Report == nil {
+		return
+	}
+
+	r.Field, r.Signature = f.name, f.sig
+	opt.Report(r)
+}
+
+// Read reads the fields of p tagged with `memory:"..."`, see Compile. The
+// plan for the types of addresses and p is compiled on the first call and
+// reused after that.
+func Read(r io.ReaderAt, addresses interface{}, p interface{}) error {
+	plan, err := cachedPlan(addresses, p)
+	if err != nil {
+		return err
+	}
+
+	return plan.Read(r, addresses, p)
 }
 
 func readPrimitive(r io.ReaderAt, p interface{}, addr int64, offsets ...int64) error {
 	var err error
 
 	switch p := p.(type) {
+	case *bool:
+		*p, err = ReadBool(r, addr, offsets...)
 	case *int8:
 		*p, err = ReadInt8(r, addr, offsets...)
 	case *int16:
@@ -318,107 +516,3 @@ func readPrimitive(r io.ReaderAt, p interface{}, addr int64, offsets ...int64) e
 	return err
 
 }
-
-type mem struct {
-	Child  *mem
-	Offset int64
-}
-
-func (m *mem) String() string {
-	var b strings.Builder
-
-	if m.Child != nil {
-		_, _ = fmt.Fprintf(&b, "[%s]", m.Child)
-	}
-
-	if m.Child != nil && m.Offset != 0 {
-		b.WriteString(" + ")
-	}
-
-	if m.Offset != 0 {
-		_, _ = fmt.Fprintf(&b, "0x%x", m.Offset)
-	}
-
-	return b.String()
-}
-
-func (m *mem) eval(f func(p int64) (int64, error)) (int64, error) {
-	if m.Child != nil {
-		childAddr, err := m.Child.eval(f)
-		if err != nil {
-			return 0, err
-		}
-
-		dereferenced, err := f(childAddr)
-		if err != nil {
-			return 0, err
-		}
-
-		return dereferenced + m.Offset, nil
-	} else {
-		return m.Offset, nil
-	}
-}
-
-func parseMem(tag string,
-	varFunc func(name string) (int64, error)) (*mem, error) {
-
-	var s scanner.Scanner
-	s.Init(strings.NewReader(tag))
-	s.Mode = scanner.ScanIdents | scanner.ScanInts
-
-	return parseMemExpr(&s, varFunc, false)
-}
-
-func parseMemExpr(s *scanner.Scanner,
-	varFunc func(name string) (int64, error), inBrackets bool) (*mem, error) {
-	expr := &mem{}
-	switch tok := s.Scan(); tok {
-	case '[':
-		inner, err := parseMemExpr(s, varFunc, true)
-		if err != nil {
-			return nil, err
-		}
-		expr.Child = inner
-	case scanner.Ident:
-		name := s.TokenText()
-		var err error
-		expr.Offset, err = varFunc(name)
-		if err != nil {
-			return nil, err
-		}
-	case scanner.Int:
-		var err error
-		expr.Offset, err = strconv.ParseInt(s.TokenText(), 0, 64)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("unexpected token %d (%s)",
-			tok, s.TokenText())
-	}
-
-	switch tok := s.Scan(); tok {
-	case '+', '-':
-		rest, err := parseMemExpr(s, varFunc, inBrackets)
-		if err != nil {
-			return nil, err
-		}
-		switch tok {
-		case '+':
-			expr.Offset += rest.Offset
-		case '-':
-			expr.Offset -= rest.Offset
-		}
-		return expr, nil
-	case scanner.EOF, ']':
-		if tok == ']' && !inBrackets {
-			return nil, fmt.Errorf("unexpected token %d (%s)",
-				tok, s.TokenText())
-		}
-		return expr, nil
-	default:
-		return nil, fmt.Errorf("unexpected token %d (%s)",
-			tok, s.TokenText())
-	}
-}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// strictAddresses has one sig field for every outcome of a strict scan of
+// newStrictProcess.
+type strictAddresses struct {
+	Unique  int64 `sig:"11 22 33 ( ?? ?? ?? ?? )"`
+	Same    int64 `sig:"44 55 66 ( ?? ?? ?? ?? )"`
+	Ambig   int64 `sig:"77 88 99 ( ?? ?? ?? ?? )"`
+	Missing int64 `sig:"AA BB CC DD"`
+}
+
+// newStrictProcess maps two regions. The Same pattern is in both and
+// references the same global, the Ambig pattern in both references
+// different ones.
+func newStrictProcess() *fakeProcess {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x10000, 0x1000).prot = ProtRead | ProtExec
+	p.mapRegion(0x20000, 0x1000).prot = ProtRead | ProtExec
+
+	p.write(0x10010, []byte{0x11, 0x22, 0x33, 0x00, 0x50, 0x00, 0x00})
+	p.write(0x10020, []byte{0x44, 0x55, 0x66, 0x00, 0x60, 0x00, 0x00})
+	p.write(0x20020, []byte{0x44, 0x55, 0x66, 0x00, 0x60, 0x00, 0x00})
+	p.write(0x10030, []byte{0x77, 0x88, 0x99, 0x00, 0x70, 0x00, 0x00})
+	p.write(0x20030, []byte{0x77, 0x88, 0x99, 0x00, 0x71, 0x00, 0x00})
+
+	return p
+}
+
+func TestScanAll(t *testing.T) {
+	p := newStrictProcess()
+
+	matches, err := ScanAll(p, "77 88 99 ( ?? ?? ?? ?? )")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ addr, resolved, region int64 }{
+		{0x10030, 0x7000, 0x10000},
+		{0x20030, 0x7100, 0x20000},
+	}
+
+	if len(matches) != len(want) {
+		t.Fatalf("ScanAll = %+v, want %d matches", matches, len(want))
+	}
+
+	for i, m := range matches {
+		if m.Address != want[i].addr || m.Resolved != want[i].resolved || m.Region.Start() != want[i].region {
+			t.Errorf("match %d at 0x%x resolving to 0x%x in 0x%x, want %+v",
+				i, m.Address, m.Resolved, m.Region.Start(), want[i])
+		}
+	}
+
+	if matches, err := ScanAll(p, "AA BB CC DD"); err != nil || len(matches) != 0 {
+		t.Errorf("ScanAll of a missing pattern = %+v, %v", matches, err)
+	}
+}
+
+func TestResolvePatternsStrict(t *testing.T) {
+	tests := []struct {
+		mode      StrictMode
+		ambig     int64
+		ambigErr  bool
+		ambigSeen int
+	}{
+		{StrictOff, 0x7000, false, 1},
+		{StrictWarn, 0x7000, false, 2},
+		{StrictFail, 0, true, 2},
+	}
+
+	for _, tt := range tests {
+		reports := map[string]SignatureReport{}
+		opt := &ScanOptions{Strict: tt.mode, Workers: 1, Report: func(r SignatureReport) {
+			reports[r.Field] = r
+		}}
+
+		var addrs strictAddresses
+		err := ResolvePatternsContext(context.Background(), newStrictProcess(), &addrs, opt)
+
+		want := strictAddresses{Unique: 0x5000, Same: 0x6000, Ambig: tt.ambig}
+		if addrs != want {
+			t.Errorf("mode %d: resolved %+v, want %+v", tt.mode, addrs, want)
+		}
+
+		// the failure of Missing doesn't hide the one of Ambig
+		if !errors.Is(err, ErrPatternNotFound) || errors.Is(err, ErrAmbiguousPattern) != tt.ambigErr {
+			t.Errorf("mode %d: ResolvePatterns = %v", tt.mode, err)
+		}
+
+		if r := reports["Missing"]; r.Alternative != -1 || !errors.Is(r.Err, ErrPatternNotFound) {
+			t.Errorf("mode %d: Missing report %+v", tt.mode, r)
+		}
+
+		ambig := reports["Ambig"]
+		if len(ambig.Matches) != tt.ambigSeen || (ambig.Err != nil) != tt.ambigErr {
+			t.Errorf("mode %d: Ambig report %+v", tt.mode, ambig)
+		}
+
+		if tt.mode == StrictOff {
+			continue
+		}
+
+		if !ambig.Ambiguous() || len(ambig.Regions()) != 2 {
+			t.Errorf("mode %d: Ambig report in %d regions, ambiguous %v", tt.mode, len(ambig.Regions()), ambig.Ambiguous())
+		}
+
+		// two matches of the same global are fine
+		same := reports["Same"]
+		if len(same.Matches) != 2 || same.Ambiguous() || same.Err != nil || len(same.Regions()) != 2 {
+			t.Errorf("mode %d: Same report %+v", tt.mode, same)
+		}
+
+		if unique := reports["Unique"]; len(unique.Regions()) != 1 || unique.Regions()[0].Start() != 0x10000 {
+			t.Errorf("mode %d: Unique report %+v", tt.mode, unique)
+		}
+	}
+}
+
+func TestResolvePatternsFallback(t *testing.T) {
+	type fallbackAddresses struct {
+		// the capture of the first alternative can't be read
+		Broken int64 `sig:"11 22 33 ( ?? ?? ?? ?? ) | 44 55 66 ( ?? ?? ?? ?? )"`
+		// the first alternative is ambiguous in StrictFail mode
+		Ambig int64 `sig:"77 88 99 ( ?? ?? ?? ?? ) | 11 22 33 ( ?? ?? ?? ?? ) | 44 55 66 ( ?? ?? ?? ?? )"`
+		// no alternative can be used
+		Neither int64 `sig:"AA BB CC DD | 11 22 33 ( ?? ?? ?? ?? )"`
+	}
+
+	for _, mode := range []StrictMode{StrictOff, StrictFail} {
+		p := newStrictProcess()
+		p.fail = map[int64]error{0x10013: ErrUnmapped}
+
+		reports := map[string]SignatureReport{}
+		opt := &ScanOptions{Strict: mode, Workers: 1, Report: func(r SignatureReport) {
+			reports[r.Field] = r
+		}}
+
+		var addrs fallbackAddresses
+		err := ResolvePatternsContext(context.Background(), p, &addrs, opt)
+
+		if addrs.Broken != 0x6000 || reports["Broken"].Alternative != 1 {
+			t.Errorf("mode %d: Broken = 0x%x from alternative %d, want 0x6000 from 1",
+				mode, addrs.Broken, reports["Broken"].Alternative)
+		}
+
+		wantAmbig, wantAlt := int64(0x7000), 0
+		if mode == StrictFail {
+			wantAmbig, wantAlt = 0x6000, 2
+		}
+
+		if addrs.Ambig != wantAmbig || reports["Ambig"].Alternative != wantAlt {
+			t.Errorf("mode %d: Ambig = 0x%x from alternative %d, want 0x%x from %d",
+				mode, addrs.Ambig, reports["Ambig"].Alternative, wantAmbig, wantAlt)
+		}
+
+		neither := reports["Neither"]
+		if addrs.Neither != 0 || neither.Alternative != 1 || !errors.Is(neither.Err, ErrUnmapped) {
+			t.Errorf("mode %d: Neither = 0x%x, report %+v", mode, addrs.Neither, neither)
+		}
+
+		if !errors.Is(err, ErrUnmapped) || errors.Is(err, ErrAmbiguousPattern) || errors.Is(err, ErrPatternNotFound) {
+			t.Errorf("mode %d: ResolvePatterns = %v, want only the failure of Neither", mode, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lengths of the signatures GenerateSignature tries, in bytes.
+const (
+	minSignatureLength = 6
+	maxSignatureLength = 64
+)
+
+// signatureLeads are how many bytes before the target a generated signature
+// may start, for targets followed by too little unique code.
+var signatureLeads = []int{0, 8, 16, 32}
+
+var ErrNoUniqueSignature = errors.New("no unique signature found")
+
+// GenerateSignature returns a signature that matches exactly once in the
+// memory ResolvePatterns scans with opts and resolves to target, usually an
+// instruction found by hand.
+//
+// The code around target is read and the bytes that differ between runs of
+// the same build are wildcarded: operands of relative calls and jumps and
+// values pointing into mapped memory. Candidates of growing length, starting
+// at target or a few bytes before it, are then counted in a single scan and
+// the shortest unique one wins. Candidates starting before target end with
+// the adjustment leading back to it. The winner is scanned for once more to
+// make sure its only match resolves to target.
+func GenerateSignature(ctx context.Context, p Process, target int64, opts ...*ScanOptions) (string, error) {
+	opt := scanOptions(opts)
+
+	maps, err := p.Maps()
+	if err != nil {
+		return "", err
+	}
+
+	var reg Map
+	for _, m := range maps {
+		if Readable(m) && target >= m.Start() && target < m.Start()+m.Size() {
+			reg = m
+		}
+	}
+
+	if reg == nil {
+		return "", fmt.Errorf("%w: 0x%x", ErrUnmapped, target)
+	}
+
+	// a signature unique in the other regions would resolve elsewhere
+	if opt.Filter != nil && !opt.Filter(reg) {
+		return "", fmt.Errorf("%w: the filter excludes the region of 0x%x", ErrNoUniqueSignature, target)
+	}
+
+	// read the code around target, without leaving its region
+	lead := signatureLeads[len(signatureLeads)-1]
+	if avail := target - reg.Start(); avail < int64(lead) {
+		lead = int(avail)
+	}
+
+	tail := int64(maxSignatureLength)
+	if avail := reg.Start() + reg.Size() - target; avail < tail {
+		tail = avail
+	}
+
+	code := make([]byte, int64(lead)+tail)
+	if _, err := readFullAt(p, code, target-int64(lead)); err != nil {
+		return "", err
+	}
+
+	wild := volatileBytes(code, layoutOf(p).PointerSize, mappedIn(maps))
+
+	var texts []string
+	var pats []pattern
+
+	for _, l := range signatureLeads {
+		if l > lead {
+			break
+		}
+
+		for length := minSignatureLength; length <= maxSignatureLength; length++ {
+			start, end := lead-l, lead-l+length
+			if end > len(code) {
+				break
+			}
+
+			// wildcards at either end only make the signature longer
+			if wild[start] || wild[end-1] {
+				continue
+			}
+
+			text := signatureText(code[start:end], wild[start:end], l)
+
+			pat, err := parsePattern(text)
+			if err != nil {
+				continue
+			}
+
+			texts = append(texts, text)
+			pats = append(pats, pat)
+		}
+	}
+
+	if len(pats) == 0 {
+		return "", fmt.Errorf("%w: the code at 0x%x is all wildcards", ErrNoUniqueSignature, target)
+	}
+
+	s, err := newMultiScanner(pats)
+	if err != nil {
+		return "", err
+	}
+
+	counts, err := s.count(ctx, p, opt)
+	if err != nil {
+		return "", err
+	}
+
+	best := -1
+	for i, n := range counts {
+		if n == 1 && (best < 0 || len(pats[i].bytes) < len(pats[best].bytes)) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return "", fmt.Errorf("%w: every signature of up to %d bytes around 0x%x matches more than once",
+			ErrNoUniqueSignature, maxSignatureLength, target)
+	}
+
+	matches, err := ScanAllContext(ctx, p, texts[best], opt)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) != 1 || matches[0].Resolved != target {
+		return "", fmt.Errorf("%w: %s does not resolve to 0x%x alone", ErrNoUniqueSignature, texts[best], target)
+	}
+
+	return texts[best], nil
+}
+
+// volatileBytes marks the bytes of code that change between runs of the
+// same build: the rel32 operands of call, jmp and jcc and values of
+// pointer size that point into mapped memory. It looks at every offset,
+// not just instruction boundaries, so it rather wildcards too much.
+func volatileBytes(code []byte, ptrSize int, mapped func(addr int64) bool) []bool {
+	wild := make([]bool, len(code))
+
+	mark := func(start, n int) {
+		for i := start; i < start+n && i < len(wild); i++ {
+			wild[i] = true
+		}
+	}
+
+	for i := range code {
+		switch {
+		case code[i] == 0xE8 || code[i] == 0xE9:
+			mark(i+1, 4)
+		case code[i] == 0x0F && i+1 < len(code) && code[i+1]&0xF0 == 0x80:
+			mark(i+2, 4)
+		}
+
+		if i+ptrSize <= len(code) {
+			if v := int64(bytesToInt(code[i : i+ptrSize])); v != 0 && mapped(v) {
+				mark(i, ptrSize)
+			}
+		}
+	}
+
+	return wild
+}
+
+// mappedIn returns whether an address is in any of maps.
+func mappedIn(maps []Map) func(addr int64) bool {
+	sorted := append([]Map(nil), maps...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start() < sorted[j].Start()
+	})
+
+	return func(addr int64) bool {
+		i := sort.Search(len(sorted), func(i int) bool {
+			return sorted[i].Start()+sorted[i].Size() > addr
+		})
+
+		return i < len(sorted) && sorted[i].Start() <= addr
+	}
+}
+
+// signatureText formats code as a signature, wildcarding the marked bytes.
+// A lead adds the adjustment back to the address lead bytes in.
+func signatureText(code []byte, wild []bool, lead int) string {
+	var b strings.Builder
+
+	for i, c := range code {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+
+		if wild[i] {
+			b.WriteString("??")
+		} else {
+			_, _ = fmt.Fprintf(&b, "%02X", c)
+		}
+	}
+
+	if lead > 0 {
+		_, _ = fmt.Fprintf(&b, " +0x%x", lead)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestGenerateSignature(t *testing.T) {
+	code := make([]byte, 0x1000)
+	rand.New(rand.NewSource(1)).Read(code)
+
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x10000, 0x1000).prot = ProtRead | ProtExec
+	p.write(0x10000, code)
+	// a copy of the code, only the part in front of the target differs
+	p.mapRegion(0x20000, 0x1000).prot = ProtRead | ProtExec
+	p.write(0x20000, code)
+	p.write(0x20100, []byte{^code[0x100]})
+
+	const target = 0x10104
+
+	sig, err := GenerateSignature(context.Background(), p, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := ScanAll(p, sig)
+	if err != nil || len(matches) != 1 || matches[0].Resolved != target {
+		t.Errorf("ScanAll(%q) = %+v, %v, want a single match resolving to 0x%x", sig, matches, err, target)
+	}
+
+	// the copy is only found with the byte in front
+	if pat, _ := parsePattern(sig); pat.adjust == 0 {
+		t.Errorf("signature %q does not start before the target", sig)
+	}
+
+	// unique in the copy, but the copy isn't where the target is
+	copyOnly := &ScanOptions{Filter: func(m Map) bool { return m.Start() == 0x20000 }}
+	if sig, err := GenerateSignature(context.Background(), p, target, copyOnly); !errors.Is(err, ErrNoUniqueSignature) {
+		t.Errorf("GenerateSignature outside the filter = %q, %v, want ErrNoUniqueSignature", sig, err)
+	}
+
+	if _, err := GenerateSignature(context.Background(), p, 0x30000); !errors.Is(err, ErrUnmapped) {
+		t.Errorf("GenerateSignature(unmapped) = %v, want ErrUnmapped", err)
+	}
+}
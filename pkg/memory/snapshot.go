@@ -0,0 +1,375 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// A snapshot file starts with a header followed by a stream of records:
+//
+//	header:  magic [8]byte, version uint32, pid int64, time int64 (unix ns),
+//	         len uint32, executable path [len]byte
+//	region:  'R', start int64, size int64, protection uint8, state uint8,
+//	         type uint8, len uint32, module [len]byte
+//	data:    'D', addr int64, len uint32, bytes [len]byte
+//
+// Region records describe what Maps() returned at capture time, data records
+// hold the bytes that could actually be read. All integers are little endian.
+const (
+	snapshotMagic   = "BOSUSNAP"
+	snapshotVersion = 1
+
+	snapshotRegion = 'R'
+	snapshotData   = 'D'
+
+	snapshotChunkSize = 1 << 20
+	snapshotPageSize  = 4096
+)
+
+var (
+	ErrNotSnapshot     = errors.New("not a snapshot file")
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	ErrNotCaptured     = fmt.Errorf("%w, it was not captured in the snapshot", ErrUnmapped)
+)
+
+type snapshotHeader struct {
+	Pid  int64
+	Time int64
+}
+
+type snapshotRegionHeader struct {
+	Start      int64
+	Size       int64
+	Protection Protection
+	State      RegionState
+	Type       RegionType
+}
+
+// WriteSnapshot captures every readable byte of p into w.
+func WriteSnapshot(w io.Writer, p Process) error {
+	maps, err := p.Maps()
+	if err != nil {
+		return err
+	}
+
+	exe, err := p.ExecutablePath()
+	if err != nil {
+		exe = ""
+	}
+
+	bw := bufio.NewWriter(w)
+
+	_, _ = bw.WriteString(snapshotMagic)
+	_ = binary.Write(bw, binary.LittleEndian, uint32(snapshotVersion))
+	_ = binary.Write(bw, binary.LittleEndian, snapshotHeader{
+		Pid:  int64(p.Pid()),
+		Time: time.Now().UnixNano(),
+	})
+	_ = binary.Write(bw, binary.LittleEndian, uint32(len(exe)))
+	_, _ = bw.WriteString(exe)
+
+	buf := make([]byte, snapshotChunkSize)
+
+	for _, reg := range maps {
+		_ = bw.WriteByte(snapshotRegion)
+		_ = binary.Write(bw, binary.LittleEndian, snapshotRegionHeader{
+			Start:      reg.Start(),
+			Size:       reg.Size(),
+			Protection: reg.Protection(),
+			State:      reg.State(),
+			Type:       reg.Type(),
+		})
+		_ = binary.Write(bw, binary.LittleEndian, uint32(len(reg.Module())))
+		_, _ = bw.WriteString(reg.Module())
+
+		if !Readable(reg) {
+			continue
+		}
+
+		for off, end := reg.Start(), reg.Start()+reg.Size(); off < end; {
+			nToRead := end - off
+			if nToRead > snapshotChunkSize {
+				nToRead = snapshotChunkSize
+			}
+
+			n, err := p.ReadAt(buf[:nToRead], off)
+			if n > 0 {
+				_ = bw.WriteByte(snapshotData)
+				_ = binary.Write(bw, binary.LittleEndian, off)
+				_ = binary.Write(bw, binary.LittleEndian, uint32(n))
+				if _, err := bw.Write(buf[:n]); err != nil {
+					return err
+				}
+			}
+
+			if err != nil || n == 0 {
+				// skip the page that failed to read
+				n = int((off+int64(n))/snapshotPageSize*snapshotPageSize + snapshotPageSize - off)
+			}
+
+			off += int64(n)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// fileChunk maps a range of captured memory to its position in a file.
+type fileChunk struct {
+	addr    int64
+	size    int64
+	fileOff int64
+}
+
+// readFileChunks serves a ReadAt at off from chunks sorted by address.
+// Bytes not covered by any chunk fail with missing.
+func readFileChunks(f io.ReaderAt, chunks []fileChunk, b []byte, off int64,
+	missing error) (n int, err error) {
+	i := sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].addr+chunks[i].size > off
+	})
+
+	for n < len(b) {
+		if i >= len(chunks) || chunks[i].addr > off+int64(n) {
+			return n, missing
+		}
+
+		c := chunks[i]
+		rel := off + int64(n) - c.addr
+		nToRead := c.size - rel
+		if rem := int64(len(b) - n); nToRead > rem {
+			nToRead = rem
+		}
+
+		nn, err := f.ReadAt(b[n:n+int(nToRead)], c.fileOff+rel)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+
+		i++
+	}
+
+	return n, nil
+}
+
+// Snapshot is a Process backed by a file written by WriteSnapshot.
+type Snapshot struct {
+	f       *os.File
+	pid     int
+	time    time.Time
+	exe     string
+	regions []mapInfo
+	chunks  []fileChunk
+}
+
+// OpenSnapshot opens a snapshot file and indexes its records. The captured
+// bytes themselves stay on disk.
+func OpenSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := readSnapshot(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func readSnapshot(f *os.File) (*Snapshot, error) {
+	r := bufio.NewReader(f)
+	pos := int64(0)
+
+	read := func(data interface{}) error {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return err
+		}
+		pos += int64(binary.Size(data))
+		return nil
+	}
+
+	var magic [len(snapshotMagic)]byte
+	if err := read(&magic); err != nil || string(magic[:]) != snapshotMagic {
+		return nil, ErrNotSnapshot
+	}
+
+	var version uint32
+	if err := read(&version); err != nil {
+		return nil, err
+	}
+
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w %d", ErrSnapshotVersion, version)
+	}
+
+	var hdr snapshotHeader
+	var exeLen uint32
+	if err := read(&hdr); err != nil {
+		return nil, err
+	}
+	if err := read(&exeLen); err != nil {
+		return nil, err
+	}
+
+	exe := make([]byte, exeLen)
+	if err := read(exe); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		f:    f,
+		pid:  int(hdr.Pid),
+		time: time.Unix(0, hdr.Time),
+		exe:  string(exe),
+	}
+
+	for {
+		var kind byte
+		if err := read(&kind); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		switch kind {
+		case snapshotRegion:
+			reg, err := readSnapshotRegion(read)
+			if err != nil {
+				return nil, err
+			}
+			s.regions = append(s.regions, reg)
+		case snapshotData:
+			var addr int64
+			var size uint32
+			if err := read(&addr); err != nil {
+				return nil, err
+			}
+			if err := read(&size); err != nil {
+				return nil, err
+			}
+
+			s.chunks = append(s.chunks, fileChunk{addr, int64(size), pos})
+
+			if _, err := r.Discard(int(size)); err != nil {
+				return nil, err
+			}
+			pos += int64(size)
+		default:
+			return nil, fmt.Errorf("unknown snapshot record %q at offset %d", kind, pos-1)
+		}
+	}
+
+	sort.Slice(s.regions, func(i, j int) bool {
+		return s.regions[i].start < s.regions[j].start
+	})
+
+	sort.Slice(s.chunks, func(i, j int) bool {
+		return s.chunks[i].addr < s.chunks[j].addr
+	})
+
+	return s, nil
+}
+
+func readSnapshotRegion(read func(data interface{}) error) (mapInfo, error) {
+	var hdr snapshotRegionHeader
+	var moduleLen uint32
+	if err := read(&hdr); err != nil {
+		return mapInfo{}, err
+	}
+	if err := read(&moduleLen); err != nil {
+		return mapInfo{}, err
+	}
+
+	module := make([]byte, moduleLen)
+	if err := read(module); err != nil {
+		return mapInfo{}, err
+	}
+
+	return mapInfo{
+		start:  hdr.Start,
+		size:   hdr.Size,
+		prot:   hdr.Protection,
+		state:  hdr.State,
+		type_:  hdr.Type,
+		module: string(module),
+	}, nil
+}
+
+// Time returns when the snapshot was captured.
+func (s *Snapshot) Time() time.Time {
+	return s.time
+}
+
+func (s *Snapshot) ExecutablePath() (string, error) {
+	return s.exe, nil
+}
+
+func (s *Snapshot) Close() error {
+	return s.f.Close()
+}
+
+func (s *Snapshot) Pid() int {
+	return s.pid
+}
+
+func (s *Snapshot) ReadAt(b []byte, off int64) (n int, err error) {
+	return readFileChunks(s.f, s.chunks, b, off, ErrNotCaptured)
+}
+
+// Maps returns the captured address ranges with the attributes of the region
+// they were captured from. Adjacent data records of the same region are
+// merged, holes left by unreadable pages split a region.
+func (s *Snapshot) Maps() ([]Map, error) {
+	var maps []Map
+	var last *mapInfo
+
+	for _, c := range s.chunks {
+		i := s.region(c.addr)
+
+		if last != nil && last.start+last.size == c.addr && i >= 0 && i == s.region(last.start) {
+			last.size += c.size
+			continue
+		}
+
+		if last != nil {
+			maps = append(maps, *last)
+		}
+
+		m := mapInfo{start: c.addr, size: c.size, prot: ProtRead}
+		if i >= 0 {
+			m = s.regions[i]
+			m.start, m.size = c.addr, c.size
+		}
+		last = &m
+	}
+
+	if last != nil {
+		maps = append(maps, *last)
+	}
+
+	return maps, nil
+}
+
+// region returns the index of the region record containing addr or -1.
+func (s *Snapshot) region(addr int64) int {
+	i := sort.Search(len(s.regions), func(i int) bool {
+		return s.regions[i].start+s.regions[i].size > addr
+	})
+
+	if i < len(s.regions) && s.regions[i].start <= addr {
+		return i
+	}
+
+	return -1
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// holeyProcess reports maps of its own while the fake process behind it only
+// backs parts of them, like pages that fail to read in a live process.
+type holeyProcess struct {
+	*fakeProcess
+	maps []Map
+}
+
+func (p holeyProcess) Maps() ([]Map, error) {
+	return p.maps, nil
+}
+
+func TestSnapshot(t *testing.T) {
+	fake := newFakeProcess(Layout{})
+	// the first region can't be read past 0x11800, the second not before
+	// 0x21000
+	fake.mapRegion(0x10000, 0x1800)
+	fake.mapRegion(0x12000, 0x1000)
+	fake.mapRegion(0x21000, 0x1000)
+	fake.mapRegion(0x30000, 0x1000)
+	fake.write(0x10ffe, []byte{1, 2, 3, 4})
+	fake.write(0x12000, []byte{5, 6})
+	fake.write(0x21000, []byte{7, 8})
+
+	p := holeyProcess{fake, []Map{
+		mapInfo{start: 0x10000, size: 0x3000, prot: ProtRead | ProtWrite, module: "a.dll"},
+		mapInfo{start: 0x20000, size: 0x2000, prot: ProtRead | ProtExec, type_: TypeImage},
+		// never read, it isn't readable
+		mapInfo{start: 0x30000, size: 0x1000},
+	}}
+
+	path := filepath.Join(t.TempDir(), "test.snap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteSnapshot(f, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if exe, _ := s.ExecutablePath(); s.Pid() != 1234 || exe != `C:\osu!\osu!.exe` {
+		t.Errorf("snapshot pid %d exe %q", s.Pid(), exe)
+	}
+
+	if s.Time().IsZero() {
+		t.Error("snapshot has no capture time")
+	}
+
+	maps, err := s.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMaps := []Map{
+		mapInfo{start: 0x10000, size: 0x1800, prot: ProtRead | ProtWrite, module: "a.dll"},
+		mapInfo{start: 0x12000, size: 0x1000, prot: ProtRead | ProtWrite, module: "a.dll"},
+		mapInfo{start: 0x21000, size: 0x1000, prot: ProtRead | ProtExec, type_: TypeImage},
+	}
+	if !reflect.DeepEqual(maps, wantMaps) {
+		t.Errorf("snapshot maps %+v, want %+v", maps, wantMaps)
+	}
+
+	reads := []struct {
+		off  int64
+		n    int
+		want []byte
+		err  error
+	}{
+		{0x10ffe, 4, []byte{1, 2, 3, 4}, nil},
+		{0x12000, 2, []byte{5, 6}, nil},
+		{0x21000, 2, []byte{7, 8}, nil},
+		{0x117fe, 4, []byte{0, 0}, ErrNotCaptured},
+		{0x20000, 2, nil, ErrNotCaptured},
+		{0x30000, 2, nil, ErrNotCaptured},
+	}
+
+	for _, r := range reads {
+		b := make([]byte, r.n)
+		n, err := s.ReadAt(b, r.off)
+		if !bytes.Equal(b[:n], r.want) || !errors.Is(err, r.err) || (r.err == nil) != (err == nil) {
+			t.Errorf("ReadAt(0x%x) = % X, %v, want % X, %v", r.off, b[:n], err, r.want, r.err)
+		}
+	}
+
+	if !errors.Is(ErrNotCaptured, ErrUnmapped) {
+		t.Error("ErrNotCaptured is not an ErrUnmapped")
+	}
+}
+
+func TestOpenSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{"BOSUTRCE", ErrNotSnapshot},
+		{snapshotMagic + "\x07\x00\x00\x00", ErrSnapshotVersion},
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OpenSnapshot(path); !errors.Is(err, tt.err) {
+			t.Errorf("OpenSnapshot(%q) = %v, want %v", tt.data, err, tt.err)
+		}
+	}
+}
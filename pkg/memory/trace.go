@@ -0,0 +1,471 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A trace file starts with a header followed by one event per call:
+//
+//	header:  magic [8]byte, version uvarint, pid uvarint, executable path,
+//	         layout (pointer size uvarint, 6 * offset varint)
+//	read:    'r', elapsed uvarint (ns), off varint, len uvarint, n uvarint,
+//	         error, bytes [n]byte
+//	maps:    'm', elapsed uvarint (ns), count uvarint, count * (start varint,
+//	         size varint, protection byte, state byte, type byte, module),
+//	         error
+//
+// Strings are a uvarint length followed by the bytes. Errors are a kind byte
+// (0 = nil, 1 = io.EOF, 2 = other, 3 = ErrUnmapped, 4 = ErrProcessExited)
+// with the message string for the kinds after io.EOF.
+const (
+	traceMagic   = "BOSUTRCE"
+	traceVersion = 1
+
+	traceRead = 'r'
+	traceMaps = 'm'
+
+	traceErrNil   = 0
+	traceErrEOF   = 1
+	traceErrOther = 2
+
+	traceErrUnmapped = 3
+	traceErrExited   = 4
+)
+
+var (
+	ErrNotTrace      = errors.New("not a trace file")
+	ErrTraceVersion  = errors.New("unsupported trace version")
+	ErrTraceEnd      = errors.New("end of trace")
+	ErrTraceMismatch = errors.New("call does not match the trace")
+)
+
+// Recorder is a Process that forwards every call to another Process and
+// writes the calls and their results to a trace, see NewReplayer.
+type Recorder struct {
+	p     Process
+	mu    sync.Mutex
+	w     *bufio.Writer
+	start time.Time
+	buf   []byte
+}
+
+// NewRecorder starts recording the calls made to p into w. Close flushes the
+// trace and closes p, w has to be closed by the caller.
+func NewRecorder(p Process, w io.Writer) (*Recorder, error) {
+	exe, err := p.ExecutablePath()
+	if err != nil {
+		exe = ""
+	}
+
+	rec := &Recorder{p: p, w: bufio.NewWriter(w), start: time.Now()}
+
+	rec.buf = append(rec.buf, traceMagic...)
+	rec.buf = binary.AppendUvarint(rec.buf, traceVersion)
+	rec.buf = binary.AppendUvarint(rec.buf, uint64(p.Pid()))
+	rec.buf = appendTraceString(rec.buf, exe)
+	rec.buf = appendTraceLayout(rec.buf, layoutOf(p))
+
+	if err := rec.flushEvent(); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func appendTraceString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendTraceLayout appends the pointer size and the object offsets of l in
+// the order they are declared in Layout.
+func appendTraceLayout(b []byte, l Layout) []byte {
+	b = binary.AppendUvarint(b, uint64(l.PointerSize))
+	for _, off := range []int64{l.StringLength, l.StringData, l.ListItems,
+		l.ListSize, l.ArrayLength, l.ArrayData} {
+		b = binary.AppendVarint(b, off)
+	}
+
+	return b
+}
+
+func appendTraceError(b []byte, err error) []byte {
+	switch err {
+	case nil:
+		return append(b, traceErrNil)
+	case io.EOF:
+		return append(b, traceErrEOF)
+	}
+
+	switch {
+	case errors.Is(err, ErrUnmapped):
+		return appendTraceString(append(b, traceErrUnmapped), err.Error())
+	case errors.Is(err, ErrProcessExited):
+		return appendTraceString(append(b, traceErrExited), err.Error())
+	default:
+		return appendTraceString(append(b, traceErrOther), err.Error())
+	}
+}
+
+// replayedError is an error read from a trace. It has the message of the
+// recorded error and wraps its kind.
+type replayedError struct {
+	msg  string
+	kind error
+}
+
+func (e *replayedError) Error() string {
+	return e.msg
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.kind
+}
+
+func (rec *Recorder) flushEvent() error {
+	_, err := rec.w.Write(rec.buf)
+	rec.buf = rec.buf[:0]
+	return err
+}
+
+func (rec *Recorder) elapsed() uint64 {
+	return uint64(time.Since(rec.start))
+}
+
+func (rec *Recorder) ReadAt(b []byte, off int64) (n int, err error) {
+	n, err = rec.p.ReadAt(b, off)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.buf = append(rec.buf, traceRead)
+	rec.buf = binary.AppendUvarint(rec.buf, rec.elapsed())
+	rec.buf = binary.AppendVarint(rec.buf, off)
+	rec.buf = binary.AppendUvarint(rec.buf, uint64(len(b)))
+	rec.buf = binary.AppendUvarint(rec.buf, uint64(n))
+	rec.buf = appendTraceError(rec.buf, err)
+	rec.buf = append(rec.buf, b[:n]...)
+
+	if werr := rec.flushEvent(); werr != nil {
+		return n, werr
+	}
+
+	return n, err
+}
+
+func (rec *Recorder) Maps() ([]Map, error) {
+	maps, err := rec.p.Maps()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.buf = append(rec.buf, traceMaps)
+	rec.buf = binary.AppendUvarint(rec.buf, rec.elapsed())
+	rec.buf = binary.AppendUvarint(rec.buf, uint64(len(maps)))
+	for _, m := range maps {
+		rec.buf = binary.AppendVarint(rec.buf, m.Start())
+		rec.buf = binary.AppendVarint(rec.buf, m.Size())
+		rec.buf = append(rec.buf, byte(m.Protection()), byte(m.State()), byte(m.Type()))
+		rec.buf = appendTraceString(rec.buf, m.Module())
+	}
+	rec.buf = appendTraceError(rec.buf, err)
+
+	if werr := rec.flushEvent(); werr != nil {
+		return maps, werr
+	}
+
+	return maps, err
+}
+
+func (rec *Recorder) Pid() int {
+	return rec.p.Pid()
+}
+
+func (rec *Recorder) ExecutablePath() (string, error) {
+	return rec.p.ExecutablePath()
+}
+
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	err := rec.w.Flush()
+	rec.mu.Unlock()
+
+	if cerr := rec.p.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Replayer is a Process that answers calls from a trace written by a
+// Recorder. Calls have to arrive in the order they were recorded, anything
+// else fails with ErrTraceMismatch. Scans should therefore use a single
+// worker both when recording and when replaying.
+type Replayer struct {
+	mu       sync.Mutex
+	r        *bufio.Reader
+	pid      int
+	exe      string
+	layout   Layout
+	realtime bool
+	start    time.Time
+}
+
+// NewReplayer reads the trace header from r. With realtime set every call
+// blocks until as much time has passed since the replay started as had
+// passed in the recording.
+func NewReplayer(r io.Reader, realtime bool) (*Replayer, error) {
+	rep := &Replayer{r: bufio.NewReader(r), realtime: realtime}
+
+	var magic [len(traceMagic)]byte
+	if _, err := io.ReadFull(rep.r, magic[:]); err != nil || string(magic[:]) != traceMagic {
+		return nil, ErrNotTrace
+	}
+
+	version, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != traceVersion {
+		return nil, fmt.Errorf("%w %d", ErrTraceVersion, version)
+	}
+
+	pid, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return nil, err
+	}
+
+	rep.exe, err = rep.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	if rep.layout, err = rep.readLayout(); err != nil {
+		return nil, err
+	}
+
+	rep.pid = int(pid)
+	rep.start = time.Now()
+
+	return rep, nil
+}
+
+func (rep *Replayer) readString() (string, error) {
+	n, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rep.r, b); err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (rep *Replayer) readLayout() (Layout, error) {
+	ptrSize, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	l := Layout{PointerSize: int(ptrSize)}
+	for _, off := range []*int64{&l.StringLength, &l.StringData, &l.ListItems,
+		&l.ListSize, &l.ArrayLength, &l.ArrayData} {
+		if *off, err = binary.ReadVarint(rep.r); err != nil {
+			return Layout{}, err
+		}
+	}
+
+	return l, nil
+}
+
+func (rep *Replayer) readError() (error, error) {
+	kind, err := rep.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case traceErrNil:
+		return nil, nil
+	case traceErrEOF:
+		return io.EOF, nil
+	case traceErrOther, traceErrUnmapped, traceErrExited:
+		msg, err := rep.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case traceErrUnmapped:
+			return &replayedError{msg, ErrUnmapped}, nil
+		case traceErrExited:
+			return &replayedError{msg, ErrProcessExited}, nil
+		default:
+			return errors.New(msg), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace error kind %d", kind)
+	}
+}
+
+// next reads the header of the next event and waits for its time when
+// replaying in realtime.
+func (rep *Replayer) next(want byte) error {
+	kind, err := rep.r.ReadByte()
+	if err == io.EOF {
+		return ErrTraceEnd
+	} else if err != nil {
+		return err
+	}
+
+	if kind != want {
+		return fmt.Errorf("%w: expected %q event, trace has %q", ErrTraceMismatch, want, kind)
+	}
+
+	elapsed, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return err
+	}
+
+	if rep.realtime {
+		time.Sleep(time.Until(rep.start.Add(time.Duration(elapsed))))
+	}
+
+	return nil
+}
+
+func (rep *Replayer) ReadAt(b []byte, off int64) (n int, err error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if err := rep.next(traceRead); err != nil {
+		return 0, err
+	}
+
+	recOff, err := binary.ReadVarint(rep.r)
+	if err != nil {
+		return 0, err
+	}
+
+	recLen, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return 0, err
+	}
+
+	recN, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return 0, err
+	}
+
+	recErr, err := rep.readError()
+	if err != nil {
+		return 0, err
+	}
+
+	data := make([]byte, recN)
+	if _, err := io.ReadFull(rep.r, data); err != nil {
+		return 0, err
+	}
+
+	if recOff != off || recLen != uint64(len(b)) {
+		return 0, fmt.Errorf("%w: read of %d bytes at 0x%x, trace has %d bytes at 0x%x",
+			ErrTraceMismatch, len(b), off, recLen, recOff)
+	}
+
+	return copy(b, data), recErr
+}
+
+func (rep *Replayer) Maps() ([]Map, error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if err := rep.next(traceMaps); err != nil {
+		return nil, err
+	}
+
+	count, err := binary.ReadUvarint(rep.r)
+	if err != nil {
+		return nil, err
+	}
+
+	maps := make([]Map, count)
+	for i := range maps {
+		if maps[i], err = rep.readMap(); err != nil {
+			return nil, err
+		}
+	}
+
+	recErr, err := rep.readError()
+	if err != nil {
+		return nil, err
+	}
+
+	return maps, recErr
+}
+
+func (rep *Replayer) Pid() int {
+	return rep.pid
+}
+
+func (rep *Replayer) ExecutablePath() (string, error) {
+	return rep.exe, nil
+}
+
+func (rep *Replayer) Close() error {
+	return nil
+}
+
+// Layout returns the layout of the recorded process.
+func (rep *Replayer) Layout() Layout {
+	return rep.layout
+}
+
+func (rep *Replayer) readMap() (Map, error) {
+	var m mapInfo
+	var err error
+
+	if m.start, err = binary.ReadVarint(rep.r); err != nil {
+		return nil, err
+	}
+	if m.size, err = binary.ReadVarint(rep.r); err != nil {
+		return nil, err
+	}
+
+	var attrs [3]byte
+	if _, err := io.ReadFull(rep.r, attrs[:]); err != nil {
+		return nil, err
+	}
+	m.prot, m.state, m.type_ = Protection(attrs[0]), RegionState(attrs[1]), RegionType(attrs[2])
+
+	if m.module, err = rep.readString(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (rec *Recorder) Alive() bool {
+	return isAlive(rec.p)
+}
+
+func (rec *Recorder) StartTime() (time.Time, error) {
+	if s, ok := rec.p.(started); ok {
+		return s.StartTime()
+	}
+
+	return time.Time{}, errors.New("process start time unknown")
+}
+
+func (rec *Recorder) Layout() Layout {
+	return layoutOf(rec.p)
+}
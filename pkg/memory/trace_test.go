@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// recordTrace records a Maps call followed by the reads of TestTrace on a
+// 64-bit fake process.
+func recordTrace(t *testing.T) []byte {
+	t.Helper()
+
+	p := newFakeProcess(Layout64)
+	p.mapRegion(0x1000, 0x100).module = `C:\osu!\osu!.exe`
+	reg := p.mapRegion(0x2000, 0x100)
+	reg.prot, reg.type_ = ProtRead|ProtExec, TypeImage
+	p.write(0x1000, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	p.write(0x10fc, []byte{9, 10, 11, 12})
+	p.fail = map[int64]error{0x2000: io.EOF}
+
+	var buf bytes.Buffer
+	rec, err := NewRecorder(p, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rec.Maps(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 8)
+	_, _ = rec.ReadAt(b, 0x1000)
+	_, _ = rec.ReadAt(b, 0x10fc)
+	_, _ = rec.ReadAt(b, 0x2000)
+	p.exited = true
+	_, _ = rec.ReadAt(b, 0x1000)
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestTrace(t *testing.T) {
+	rep, err := NewReplayer(bytes.NewReader(recordTrace(t)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exe, _ := rep.ExecutablePath(); rep.Pid() != 1234 || exe != `C:\osu!\osu!.exe` {
+		t.Errorf("replayed pid %d exe %q", rep.Pid(), exe)
+	}
+
+	if !reflect.DeepEqual(rep.Layout(), Layout64) {
+		t.Errorf("replayed layout %+v, want Layout64", rep.Layout())
+	}
+
+	maps, err := rep.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMaps := []Map{
+		mapInfo{start: 0x1000, size: 0x100, prot: ProtRead | ProtWrite, module: `C:\osu!\osu!.exe`},
+		mapInfo{start: 0x2000, size: 0x100, prot: ProtRead | ProtExec, type_: TypeImage},
+	}
+	if !reflect.DeepEqual(maps, wantMaps) {
+		t.Errorf("replayed maps %+v, want %+v", maps, wantMaps)
+	}
+
+	reads := []struct {
+		off  int64
+		want []byte
+		err  error
+	}{
+		{0x1000, []byte{1, 2, 3, 4, 5, 6, 7, 8}, nil},
+		{0x10fc, []byte{9, 10, 11, 12}, ErrUnmapped},
+		{0x2000, nil, io.EOF},
+		{0x1000, nil, ErrProcessExited},
+	}
+
+	for _, r := range reads {
+		b := make([]byte, 8)
+		n, err := rep.ReadAt(b, r.off)
+		if !bytes.Equal(b[:n], r.want) {
+			t.Errorf("ReadAt(0x%x) = % X, want % X", r.off, b[:n], r.want)
+		}
+
+		if (r.err == nil) != (err == nil) || !errors.Is(err, r.err) {
+			t.Errorf("ReadAt(0x%x) error %v, want %v", r.off, err, r.err)
+		}
+	}
+
+	if _, err := rep.ReadAt(make([]byte, 8), 0x1000); !errors.Is(err, ErrTraceEnd) {
+		t.Errorf("ReadAt past the end = %v, want ErrTraceEnd", err)
+	}
+}
+
+func TestTraceMismatch(t *testing.T) {
+	trace := recordTrace(t)
+
+	rep, err := NewReplayer(bytes.NewReader(trace), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rep.ReadAt(make([]byte, 8), 0x1000); !errors.Is(err, ErrTraceMismatch) {
+		t.Errorf("ReadAt before Maps = %v, want ErrTraceMismatch", err)
+	}
+
+	rep, err = NewReplayer(bytes.NewReader(trace), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rep.Maps(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rep.ReadAt(make([]byte, 8), 0x10fc); !errors.Is(err, ErrTraceMismatch) {
+		t.Errorf("ReadAt at another offset = %v, want ErrTraceMismatch", err)
+	}
+
+	if _, err := rep.ReadAt(make([]byte, 4), 0x10fc); !errors.Is(err, ErrTraceMismatch) {
+		t.Errorf("ReadAt of another length = %v, want ErrTraceMismatch", err)
+	}
+}
+
+func TestReplayerHeader(t *testing.T) {
+	if _, err := NewReplayer(bytes.NewReader([]byte("BOSUSNAP")), false); !errors.Is(err, ErrNotTrace) {
+		t.Errorf("NewReplayer(snapshot) = %v, want ErrNotTrace", err)
+	}
+
+	if _, err := NewReplayer(bytes.NewReader([]byte(traceMagic+"\x07")), false); !errors.Is(err, ErrTraceVersion) {
+		t.Errorf("NewReplayer(version 7) = %v, want ErrTraceVersion", err)
+	}
+}
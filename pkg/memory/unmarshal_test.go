@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var errOddXor = errors.New("odd xor")
+
+// xorValue is stored as two ints that are XORed, like the mods of osu!.
+type xorValue int32
+
+func (v *xorValue) UnmarshalMemory(r io.ReaderAt, addr int64) error {
+	a, err := ReadInt32(r, addr, 0x8)
+	if err != nil {
+		return err
+	}
+
+	b, err := ReadInt32(r, addr, 0xC)
+	if err != nil {
+		return err
+	}
+
+	if (a^b)%2 != 0 {
+		return errOddXor
+	}
+
+	*v = xorValue(a ^ b)
+	return nil
+}
+
+func TestPlanUnmarshaler(t *testing.T) {
+	var data struct {
+		Value  xorValue   `memory:"[Root + 0x18]"`
+		Dead   xorValue   `memory:"[Root + 0x10],optional"`
+		Values []xorValue `memory:"[Root + 0x1C]"`
+	}
+
+	p, addrs := newPlanProcess()
+
+	putXor := func(addr int64, v int32) {
+		p.putUint32(addr+0x8, 0x5555)
+		p.putUint32(addr+0xC, uint32(v^0x5555))
+	}
+
+	p.putPtr(0x1118, 0x1300)
+	putXor(0x1300, 24)
+
+	// List<xorValue> of four, the third one is dead and the last odd
+	p.putPtr(0x111c, 0x1500)
+	p.putPtr(0x1504, 0x1600)
+	p.putUint32(0x150c, 4)
+	p.putPtr(0x1608, 0x1700)
+	p.putPtr(0x160c, 0x1300)
+	p.putPtr(0x1610, 0x9000)
+	p.putPtr(0x1614, 0x1800)
+	putXor(0x1700, 2)
+	putXor(0x1800, 3)
+
+	err := Read(p, addrs, &data)
+
+	if data.Value != 24 || data.Dead != 0 {
+		t.Errorf("Value = %d, Dead = %d, want 24 and 0", data.Value, data.Dead)
+	}
+
+	if want := []xorValue{2, 24, 0, 0}; !reflect.DeepEqual(data.Values, want) {
+		t.Errorf("Values = %v, want %v", data.Values, want)
+	}
+
+	var rerr ReadError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Read = %v, want a ReadError", err)
+	}
+
+	if len(rerr.Optional) != 1 || rerr.Optional[0].Field != "Dead" || !errors.Is(rerr.Optional[0], ErrUnmapped) {
+		t.Errorf("optional errors %v, want Dead to be unmapped", rerr.Optional)
+	}
+
+	tests := []struct {
+		field string
+		addr  int64
+		err   error
+	}{
+		{"Values[2]", 0x9000, ErrUnmapped},
+		{"Values[3]", 0x1800, errOddXor},
+	}
+
+	if len(rerr.Required) != len(tests) {
+		t.Fatalf("required errors %v, want %d", rerr.Required, len(tests))
+	}
+
+	for i, tt := range tests {
+		ferr := rerr.Required[i]
+		if ferr.Field != tt.field || ferr.Addr != tt.addr || !errors.Is(ferr, tt.err) {
+			t.Errorf("error %d = %+v, want %s at 0x%x failing with %v", i, ferr, tt.field, tt.addr, tt.err)
+		}
+	}
+
+	// numbers decoding themselves are validated after decoding
+	var checked struct {
+		Value xorValue `memory:"[Root + 0x18]" validate:"max=10"`
+	}
+
+	if err := Read(p, addrs, &checked); !errors.Is(err, ErrImplausible) || checked.Value != 0 {
+		t.Errorf("Read of a validated unmarshaler = %v, %d, want ErrImplausible", err, checked.Value)
+	}
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrImplausible is the cause of a FieldError for a value that was read
+// but breaks a rule of its validate tag, usually because an offset is
+// wrong.
+var ErrImplausible = errors.New("implausible value")
+
+// validator checks one number read into a field.
+type validator func(v float64) error
+
+// parseValidators parses a validate tag, rules separated by spaces:
+//
+//	nonneg         v >= 0
+//	nonzero        v != 0
+//	finite         v is neither NaN nor infinite
+//	min=x, max=x   v >= x, v <= x
+//	range=x,y      x <= v <= y
+//
+// Every rule but nonzero fails for NaN.
+func parseValidators(tag string) ([]validator, error) {
+	var validators []validator
+
+	for _, rule := range strings.Fields(tag) {
+		name, args, _ := strings.Cut(rule, "=")
+
+		bounds, err := parseBounds(args)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule, err)
+		}
+
+		switch {
+		case name == "nonneg" && len(bounds) == 0:
+			validators = append(validators, bounded(0, math.Inf(1)))
+		case name == "nonzero" && len(bounds) == 0:
+			validators = append(validators, func(v float64) error {
+				if v == 0 {
+					return fmt.Errorf("%w: %v is zero", ErrImplausible, v)
+				}
+				return nil
+			})
+		case name == "finite" && len(bounds) == 0:
+			validators = append(validators, bounded(math.Inf(-1), math.Inf(1)))
+		case name == "min" && len(bounds) == 1:
+			validators = append(validators, bounded(bounds[0], math.Inf(1)))
+		case name == "max" && len(bounds) == 1:
+			validators = append(validators, bounded(math.Inf(-1), bounds[0]))
+		case name == "range" && len(bounds) == 2:
+			if bounds[0] > bounds[1] {
+				return nil, fmt.Errorf("rule %s: empty range", rule)
+			}
+			validators = append(validators, bounded(bounds[0], bounds[1]))
+		case name == "nonneg" || name == "nonzero" || name == "finite" ||
+			name == "min" || name == "max" || name == "range":
+			return nil, fmt.Errorf("rule %s: wrong number of arguments", rule)
+		default:
+			return nil, fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+
+	return validators, nil
+}
+
+func parseBounds(args string) ([]float64, error) {
+	if args == "" {
+		return nil, nil
+	}
+
+	var bounds []float64
+	for _, arg := range strings.Split(args, ",") {
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds = append(bounds, bound)
+	}
+
+	return bounds, nil
+}
+
+// bounded checks that v is between min and max, infinite bounds allow
+// infinite values but never NaN.
+func bounded(min, max float64) validator {
+	return func(v float64) error {
+		if v >= min && v <= max {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %v is outside [%v, %v]", ErrImplausible, v, min, max)
+	}
+}
+
+// isNumeric reports whether t can be validated, numbers and slices of them.
+func isNumeric(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// validate checks the number or every number of the slice v.
+func validate(v reflect.Value, validators []validator) error {
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if err := validate(v.Index(i), validators); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+
+		return nil
+	}
+
+	var f float64
+	switch {
+	case v.CanInt():
+		f = float64(v.Int())
+	case v.CanUint():
+		f = float64(v.Uint())
+	default:
+		f = v.Float()
+	}
+
+	for _, check := range validators {
+		if err := check(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value float64
+		ok    bool
+	}{
+		{"", -1, true},
+		{"nonneg", 0, true},
+		{"nonneg", -1, false},
+		{"nonneg", math.NaN(), false},
+		{"nonzero", 0, false},
+		{"nonzero", -3, true},
+		{"finite", math.Inf(1), true},
+		{"finite", math.NaN(), false},
+		{"min=10", 10, true},
+		{"min=10", 9.9, false},
+		{"max=1.5", 1.5, true},
+		{"max=1.5", 2, false},
+		{"range=0,100", 100, true},
+		{"range=0,100", 100.5, false},
+		{"range=-1,1", -1, true},
+		{"nonneg  max=10", 5, true},
+		{"nonneg max=10", 11, false},
+	}
+
+	for _, tt := range tests {
+		validators, err := parseValidators(tt.tag)
+		if err != nil {
+			t.Errorf("parseValidators(%q): %v", tt.tag, err)
+			continue
+		}
+
+		var verr error
+		for _, v := range validators {
+			if verr = v(tt.value); verr != nil {
+				break
+			}
+		}
+
+		if (verr == nil) != tt.ok || verr != nil && !errors.Is(verr, ErrImplausible) {
+			t.Errorf("%q on %v = %v, want ok %v", tt.tag, tt.value, verr, tt.ok)
+		}
+	}
+}
+
+func TestParseValidatorsErrors(t *testing.T) {
+	for _, tag := range []string{"positive", "nonneg=1", "min", "min=x", "range=1", "range=2,1", "max=1,2"} {
+		if _, err := parseValidators(tag); err == nil {
+			t.Errorf("parseValidators(%q) succeeded", tag)
+		}
+	}
+}
+
+func TestPlanValidate(t *testing.T) {
+	p, addrs := newPlanProcess()
+	p.putUint32(0x1130, 0xfffffffe)
+
+	var data struct {
+		Count    int32   `memory:"Root + 0x4" validate:"range=0,100"`
+		Delta    int16   `memory:"Root + 0x8" validate:"nonneg"`
+		Values   []int32 `memory:"[Root + 0x2C]" validate:"nonneg"`
+		Unsigned uint32  `memory:"Root + 0x30" validate:"nonzero"`
+	}
+
+	// a List<int32> of 1, -2
+	p.putPtr(0x112c, 0x1500)
+	p.putPtr(0x1504, 0x1600)
+	p.putUint32(0x150c, 2)
+	p.putUint32(0x1608, 1)
+	p.putUint32(0x160c, 0xfffffffe)
+
+	var rerr ReadError
+	if err := Read(p, addrs, &data); !errors.As(err, &rerr) {
+		t.Fatalf("Read = %v, want a ReadError", err)
+	}
+
+	if data.Count != 42 || data.Delta != 0 || data.Values != nil || data.Unsigned != 0xfffffffe {
+		t.Errorf("read %+v, want the implausible fields zeroed", data)
+	}
+
+	var failed []string
+	for _, ferr := range rerr.Required {
+		if !errors.Is(ferr, ErrImplausible) {
+			t.Errorf("%s failed with %v, want ErrImplausible", ferr.Field, ferr.Err)
+		}
+		failed = append(failed, ferr.Field)
+	}
+
+	if len(failed) != 2 || failed[0] != "Delta" || failed[1] != "Values" {
+		t.Errorf("failed fields %q, want Delta and Values", failed)
+	}
+
+	if _, err := Compile(&planAddresses{}, &struct {
+		Name string `memory:"[Root + 0xC]" validate:"nonzero"`
+	}{}); err == nil {
+		t.Error("Compile of a validated string succeeded")
+	}
+}
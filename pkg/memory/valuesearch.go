@@ -0,0 +1,536 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A value search file is a header followed by the candidates:
+//
+//	header:     magic [8]byte, version uint32, type uint8, count uint32
+//	candidate:  addr int64, raw uint64, len uint32, text [len]byte
+//
+// All integers are little endian.
+const (
+	valueSearchMagic   = "BOSUVALS"
+	valueSearchVersion = 1
+
+	// maxValueCandidates keeps a first scan for a common value like 0 from
+	// eating all memory
+	maxValueCandidates = 1 << 22
+)
+
+var (
+	ErrNotValueSearch     = errors.New("not a value search file")
+	ErrValueSearchVersion = errors.New("unsupported value search version")
+	ErrTooManyCandidates  = errors.New("too many candidates")
+	ErrInvalidComparison  = errors.New("invalid comparison")
+	ErrUnknownValueType   = errors.New("unknown value type")
+	ErrInvalidSearchValue = errors.New("invalid search value")
+)
+
+// ValueType is the type of the values a ValueSearch looks for.
+type ValueType uint8
+
+const (
+	ValueInt16 ValueType = iota + 1
+	ValueInt32
+	ValueFloat32
+	ValueFloat64
+	// ValueString is a .NET System.String, candidates are the addresses of
+	// the string objects, what a memory tag of a string field points at
+	ValueString
+)
+
+var valueTypeNames = map[ValueType]string{
+	ValueInt16:   "int16",
+	ValueInt32:   "int32",
+	ValueFloat32: "float32",
+	ValueFloat64: "float64",
+	ValueString:  "string",
+}
+
+func (t ValueType) String() string {
+	if name, ok := valueTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ValueType(%d)", uint8(t))
+}
+
+// ParseValueType returns the ValueType named like the Go type, e.g. int32.
+func ParseValueType(s string) (ValueType, error) {
+	for t, name := range valueTypeNames {
+		if strings.EqualFold(s, name) {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w %q", ErrUnknownValueType, s)
+}
+
+// size is the number of bytes of a value, 0 for strings.
+func (t ValueType) size() int {
+	switch t {
+	case ValueInt16:
+		return 2
+	case ValueInt32, ValueFloat32:
+		return 4
+	case ValueFloat64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// decode converts the raw bits of a numeric value to a float64, which holds
+// every int16 and int32 exactly.
+func (t ValueType) decode(raw uint64) float64 {
+	switch t {
+	case ValueInt16:
+		return float64(int16(raw))
+	case ValueInt32:
+		return float64(int32(raw))
+	case ValueFloat32:
+		return float64(math.Float32frombits(uint32(raw)))
+	case ValueFloat64:
+		return math.Float64frombits(raw)
+	default:
+		return 0
+	}
+}
+
+// Comparison is how NextScan narrows the candidates down.
+type Comparison uint8
+
+const (
+	// Changed keeps the candidates whose value differs from the last scan
+	Changed Comparison = iota + 1
+	// Unchanged keeps the candidates whose value is the same as in the
+	// last scan
+	Unchanged
+	// Increased keeps the candidates whose value is greater than in the
+	// last scan, not supported by strings
+	Increased
+	// Decreased keeps the candidates whose value is less than in the last
+	// scan, not supported by strings
+	Decreased
+	// Equal keeps the candidates whose value equals the one given
+	Equal
+)
+
+var comparisonNames = map[Comparison]string{
+	Changed:   "changed",
+	Unchanged: "unchanged",
+	Increased: "increased",
+	Decreased: "decreased",
+	Equal:     "equal",
+}
+
+func (c Comparison) String() string {
+	if name, ok := comparisonNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Comparison(%d)", uint8(c))
+}
+
+// ParseComparison returns the Comparison with the lowercase name s, e.g.
+// increased.
+func ParseComparison(s string) (Comparison, error) {
+	for c, name := range comparisonNames {
+		if strings.EqualFold(s, name) {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w %q", ErrInvalidComparison, s)
+}
+
+// Candidate is an address that held the value searched for in every scan
+// so far, with its value in the last one.
+type Candidate struct {
+	Addr int64
+
+	// Raw are the bits of a numeric value, Text the value of a string
+	Raw  uint64
+	Text string
+}
+
+// ValueSearch is a search for the address of a value by scanning memory
+// for it, then keeping the candidates that behave like the value in later
+// scans, e.g. increased after the value went up in the game. It can be
+// saved between scans with WriteValueSearch, so the scans can be of
+// different snapshots or runs of a tool.
+type ValueSearch struct {
+	Type       ValueType
+	Candidates []Candidate
+}
+
+// valueMatcher is a value given as text, parsed for the type searched for.
+type valueMatcher struct {
+	typ ValueType
+	raw uint64
+
+	// value and tolerance of floats, see parseSearchValue
+	f, tol float64
+
+	// text and its UTF-16 bytes of strings
+	text  string
+	utf16 []byte
+}
+
+// parseSearchValue parses the value s of type t. Floats match every value
+// that rounds to s, so 98.54 matches 98.5432 but not 98.55, which is how
+// values shown in the game are typed in.
+func parseSearchValue(t ValueType, s string) (*valueMatcher, error) {
+	m := &valueMatcher{typ: t}
+
+	switch t {
+	case ValueInt16, ValueInt32:
+		v, err := strconv.ParseInt(s, 0, t.size()*8)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSearchValue, err)
+		}
+
+		m.raw = uint64(v) & (1<<(t.size()*8) - 1)
+	case ValueFloat32, ValueFloat64:
+		v, err := strconv.ParseFloat(s, t.size()*8)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%w: %q is not a finite number", ErrInvalidSearchValue, s)
+		}
+
+		decimals := 0
+		if dot := strings.IndexByte(s, '.'); dot >= 0 && !strings.ContainsAny(s, "eE") {
+			decimals = len(s) - dot - 1
+		}
+
+		m.f, m.tol = v, 0.5*math.Pow10(-decimals)
+	case ValueString:
+		if s == "" {
+			return nil, fmt.Errorf("%w: the empty string is everywhere", ErrInvalidSearchValue)
+		}
+
+		units := utf16.Encode([]rune(s))
+		m.text = s
+		m.utf16 = make([]byte, len(units)*2)
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(m.utf16[i*2:], u)
+		}
+	default:
+		return nil, fmt.Errorf("%w %d", ErrUnknownValueType, t)
+	}
+
+	return m, nil
+}
+
+// match reports whether a candidate holds the value.
+func (m *valueMatcher) match(c Candidate) bool {
+	switch m.typ {
+	case ValueInt16, ValueInt32:
+		return c.Raw == m.raw
+	case ValueFloat32, ValueFloat64:
+		return math.Abs(m.typ.decode(c.Raw)-m.f) < m.tol
+	default:
+		return c.Text == m.text
+	}
+}
+
+// FirstValueScan scans the readable memory of p selected by opts for value,
+// written like a Go literal of type t, and returns the addresses holding it.
+// Only Filter and Progress of opts are used. Numbers are looked for at
+// aligned addresses, strings at the addresses of string objects.
+func FirstValueScan(ctx context.Context, p Process, t ValueType, value string, opts ...*ScanOptions) (*ValueSearch, error) {
+	m, err := parseSearchValue(t, value)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := scanOptions(opts)
+
+	maps, err := p.Maps()
+	if err != nil {
+		return nil, err
+	}
+
+	var regs []Map
+	for _, reg := range maps {
+		if Readable(reg) && (opt.Filter == nil || opt.Filter(reg)) {
+			regs = append(regs, reg)
+		}
+	}
+
+	var progress ScanProgress
+	reporter := progressReporter{callback: opt.Progress}
+	progress.RegionsTotal = len(regs)
+	for _, reg := range regs {
+		progress.BytesTotal += reg.Size()
+	}
+
+	layout := layoutOf(p)
+	search := &ValueSearch{Type: t}
+
+	// values of up to overlap bytes can start in one chunk and end in the
+	// next, so every chunk is read with the start of the next one
+	size, align := t.size(), t.size()
+	if t == ValueFloat64 {
+		// the 32-bit runtime only aligns doubles to 4
+		align = 4
+	}
+
+	overlap := size - 1
+	if t == ValueString {
+		overlap, align = len(m.utf16)-1, layout.PointerSize
+	}
+
+	const chunkSize = 1 << 20
+	buf := make([]byte, chunkSize+overlap)
+
+	for _, reg := range regs {
+		for off := int64(0); off < reg.Size(); off += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			chunk := buf
+			if rest := reg.Size() - off; rest < int64(len(chunk)) {
+				chunk = chunk[:rest]
+			}
+
+			n, _ := readFullAt(p, chunk, reg.Start()+off)
+			chunk = chunk[:n]
+			base := reg.Start() + off
+
+			if t == ValueString {
+				for i := 0; i < n && i < chunkSize; {
+					at := bytes.Index(chunk[i:], m.utf16)
+					if at < 0 || i+at >= chunkSize {
+						break
+					}
+
+					obj := base + int64(i+at) - layout.StringData
+					if obj%int64(align) == 0 {
+						if s, err := ReadString(p, obj); err == nil && s == m.text {
+							search.Candidates = append(search.Candidates, Candidate{Addr: obj, Text: s})
+						}
+					}
+
+					i += at + 2
+				}
+			} else {
+				for i := 0; i+size <= n && i < chunkSize; i += align {
+					c := Candidate{Addr: base + int64(i), Raw: bytesToInt(chunk[i : i+size])}
+					if m.match(c) {
+						search.Candidates = append(search.Candidates, c)
+					}
+				}
+			}
+
+			if len(search.Candidates) > maxValueCandidates {
+				return nil, fmt.Errorf("%w: %s %s is at more than %d addresses, search for a rarer value",
+					ErrTooManyCandidates, t, value, maxValueCandidates)
+			}
+
+			if rest := reg.Size() - off; rest < chunkSize {
+				progress.BytesScanned += rest
+			} else {
+				progress.BytesScanned += chunkSize
+			}
+			reporter.report(progress, false)
+		}
+
+		progress.RegionsDone++
+	}
+
+	reporter.report(progress, true)
+
+	if !isAlive(p) {
+		return nil, ErrProcessExited
+	}
+
+	return search, nil
+}
+
+// NextScan reads the candidates again from r and keeps the ones whose value
+// compares to their last one or, for Equal, to value like cmp says. value
+// is ignored by every other comparison. Candidates that can't be read
+// anymore are dropped. On error the candidates are left as they were.
+func (s *ValueSearch) NextScan(ctx context.Context, r io.ReaderAt, cmp Comparison, value string) error {
+	var m *valueMatcher
+	switch cmp {
+	case Equal:
+		var err error
+		if m, err = parseSearchValue(s.Type, value); err != nil {
+			return err
+		}
+	case Increased, Decreased:
+		if s.Type == ValueString {
+			return fmt.Errorf("%w: %s values cannot be %s", ErrInvalidComparison, s.Type, cmp)
+		}
+	case Changed, Unchanged:
+	default:
+		return fmt.Errorf("%w %d", ErrInvalidComparison, cmp)
+	}
+
+	// candidates are sorted by address, so neighbours share pages. s is
+	// only changed once the scan succeeded, a cancelled scan can still be
+	// saved and repeated
+	cache := NewPageCache(r)
+	var kept []Candidate
+
+	for i, old := range s.Candidates {
+		if i%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		c, err := s.read(cache, old.Addr)
+		if err != nil {
+			continue
+		}
+
+		var keep bool
+		switch cmp {
+		case Changed:
+			keep = c != old
+		case Unchanged:
+			keep = c == old
+		case Increased:
+			keep = s.Type.decode(c.Raw) > s.Type.decode(old.Raw)
+		case Decreased:
+			keep = s.Type.decode(c.Raw) < s.Type.decode(old.Raw)
+		case Equal:
+			keep = m.match(c)
+		}
+
+		if keep {
+			kept = append(kept, c)
+		}
+	}
+
+	if len(kept) == 0 && !isAlive(r) {
+		return ErrProcessExited
+	}
+
+	s.Candidates = kept
+	return nil
+}
+
+// read reads the candidate at addr.
+func (s *ValueSearch) read(r io.ReaderAt, addr int64) (Candidate, error) {
+	if s.Type == ValueString {
+		text, err := ReadString(r, addr)
+		return Candidate{Addr: addr, Text: text}, err
+	}
+
+	raw, err := readUintRaw(r, addr, s.Type.size())
+	return Candidate{Addr: addr, Raw: raw}, err
+}
+
+// Format returns the value of c as text.
+func (s *ValueSearch) Format(c Candidate) string {
+	switch s.Type {
+	case ValueInt16, ValueInt32:
+		return strconv.FormatInt(int64(s.Type.decode(c.Raw)), 10)
+	case ValueFloat32:
+		return strconv.FormatFloat(s.Type.decode(c.Raw), 'g', -1, 32)
+	case ValueFloat64:
+		return strconv.FormatFloat(s.Type.decode(c.Raw), 'g', -1, 64)
+	default:
+		return strconv.Quote(c.Text)
+	}
+}
+
+// WriteValueSearch writes s into w for ReadValueSearch.
+func WriteValueSearch(w io.Writer, s *ValueSearch) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = bw.WriteString(valueSearchMagic)
+	_ = binary.Write(bw, binary.LittleEndian, uint32(valueSearchVersion))
+	_ = bw.WriteByte(byte(s.Type))
+	_ = binary.Write(bw, binary.LittleEndian, uint32(len(s.Candidates)))
+
+	for _, c := range s.Candidates {
+		_ = binary.Write(bw, binary.LittleEndian, c.Addr)
+		_ = binary.Write(bw, binary.LittleEndian, c.Raw)
+		_ = binary.Write(bw, binary.LittleEndian, uint32(len(c.Text)))
+		_, _ = bw.WriteString(c.Text)
+	}
+
+	return bw.Flush()
+}
+
+// ReadValueSearch reads a search written by WriteValueSearch.
+func ReadValueSearch(r io.Reader) (*ValueSearch, error) {
+	br := bufio.NewReader(r)
+
+	read := func(data interface{}) error {
+		return binary.Read(br, binary.LittleEndian, data)
+	}
+
+	var magic [len(valueSearchMagic)]byte
+	if err := read(&magic); err != nil || string(magic[:]) != valueSearchMagic {
+		return nil, ErrNotValueSearch
+	}
+
+	var version uint32
+	if err := read(&version); err != nil {
+		return nil, err
+	}
+
+	if version != valueSearchVersion {
+		return nil, fmt.Errorf("%w %d", ErrValueSearchVersion, version)
+	}
+
+	var hdr struct {
+		Type  ValueType
+		Count uint32
+	}
+	if err := read(&hdr); err != nil {
+		return nil, err
+	}
+
+	if _, ok := valueTypeNames[hdr.Type]; !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownValueType, hdr.Type)
+	}
+
+	if hdr.Count > maxValueCandidates {
+		return nil, fmt.Errorf("%w: %d", ErrTooManyCandidates, hdr.Count)
+	}
+
+	s := &ValueSearch{Type: hdr.Type, Candidates: make([]Candidate, hdr.Count)}
+
+	for i := range s.Candidates {
+		var rec struct {
+			Addr int64
+			Raw  uint64
+			Len  uint32
+		}
+		if err := read(&rec); err != nil {
+			return nil, err
+		}
+
+		if rec.Len > MaxStringLength*4 {
+			return nil, ErrStringTooLong
+		}
+
+		text := make([]byte, rec.Len)
+		if _, err := io.ReadFull(br, text); err != nil {
+			return nil, err
+		}
+
+		s.Candidates[i] = Candidate{Addr: rec.Addr, Raw: rec.Raw, Text: string(text)}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,283 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParseValueType(t *testing.T) {
+	tests := []struct {
+		s    string
+		want ValueType
+		err  error
+	}{
+		{"int16", ValueInt16, nil},
+		{"int32", ValueInt32, nil},
+		{"Float32", ValueFloat32, nil},
+		{"float64", ValueFloat64, nil},
+		{"STRING", ValueString, nil},
+		{"int8", 0, ErrUnknownValueType},
+		{"", 0, ErrUnknownValueType},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseValueType(tt.s)
+		if got != tt.want || !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+			t.Errorf("ParseValueType(%q) = %v, %v, want %v, %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Comparison
+		err  error
+	}{
+		{"changed", Changed, nil},
+		{"unchanged", Unchanged, nil},
+		{"Increased", Increased, nil},
+		{"decreased", Decreased, nil},
+		{"EQUAL", Equal, nil},
+		{"bigger", 0, ErrInvalidComparison},
+		{"", 0, ErrInvalidComparison},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseComparison(tt.s)
+		if got != tt.want || !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+			t.Errorf("ParseComparison(%q) = %v, %v, want %v, %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestValueMatch(t *testing.T) {
+	f32 := func(f float32) uint64 { return uint64(math.Float32bits(f)) }
+	f64 := func(f float64) uint64 { return math.Float64bits(f) }
+
+	tests := []struct {
+		typ   ValueType
+		value string
+		raw   uint64
+		match bool
+	}{
+		{ValueInt32, "1234", 1234, true},
+		{ValueInt32, "1234", 1235, false},
+		{ValueInt32, "-1", 0xffffffff, true},
+		{ValueInt32, "0x10", 16, true},
+		{ValueInt16, "-1", 0xffff, true},
+		{ValueInt16, "-1", 0xffffffff, false},
+
+		// floats match everything that rounds to the decimals given
+		{ValueFloat32, "98.54", f32(98.5432), true},
+		{ValueFloat32, "98.54", f32(98.5368), true},
+		{ValueFloat32, "98.54", f32(98.55), false},
+		{ValueFloat32, "98.54", f32(98.53), false},
+		{ValueFloat64, "98.5", f64(98.54), true},
+		{ValueFloat64, "98.5", f64(98.56), false},
+		{ValueFloat64, "100", f64(99.7), true},
+		{ValueFloat64, "100", f64(100.6), false},
+		{ValueFloat64, "1e2", f64(100.4), true},
+		{ValueFloat64, "0.000", f64(0.0004), true},
+		{ValueFloat64, "0.000", f64(-0.0006), false},
+	}
+
+	for _, tt := range tests {
+		m, err := parseSearchValue(tt.typ, tt.value)
+		if err != nil {
+			t.Errorf("parseSearchValue(%v, %q): %v", tt.typ, tt.value, err)
+			continue
+		}
+
+		if got := m.match(Candidate{Raw: tt.raw}); got != tt.match {
+			t.Errorf("%v %q match %v = %v, want %v", tt.typ, tt.value, tt.typ.decode(tt.raw), got, tt.match)
+		}
+	}
+}
+
+func TestParseSearchValueErrors(t *testing.T) {
+	tests := []struct {
+		typ   ValueType
+		value string
+	}{
+		{ValueInt16, "40000"},
+		{ValueInt32, "1.5"},
+		{ValueFloat32, "NaN"},
+		{ValueFloat64, "Inf"},
+		{ValueFloat64, "fast"},
+		{ValueString, ""},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseSearchValue(tt.typ, tt.value); !errors.Is(err, ErrInvalidSearchValue) {
+			t.Errorf("parseSearchValue(%v, %q) = %v, want ErrInvalidSearchValue", tt.typ, tt.value, err)
+		}
+	}
+}
+
+// putString writes a System.String object with the text s at addr.
+func putString(p *fakeProcess, addr int64, s string) {
+	layout := p.Layout()
+	units := utf16.Encode([]rune(s))
+
+	p.putUint32(addr+layout.StringLength, uint32(len(units)))
+	for i, u := range units {
+		p.write(addr+layout.StringData+int64(2*i), []byte{byte(u), byte(u >> 8)})
+	}
+}
+
+func TestFirstValueScanString(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+
+	putString(p, 0x1000, "héllo")
+	// a longer string starting with the same text
+	putString(p, 0x1100, "héllo world")
+	// the text at an address no string object can start at
+	putString(p, 0x1202, "héllo")
+
+	s, err := FirstValueScan(context.Background(), p, ValueString, "héllo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Candidate{{Addr: 0x1000, Text: "héllo"}}
+	if !reflect.DeepEqual(s.Candidates, want) {
+		t.Errorf("candidates %+v, want %+v", s.Candidates, want)
+	}
+}
+
+func TestFirstValueScanFloat(t *testing.T) {
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+	p.putUint32(0x1000, math.Float32bits(98.5432))
+	p.putUint32(0x1004, math.Float32bits(98.55))
+	p.putUint32(0x1ffc, math.Float32bits(98.54))
+
+	s, err := FirstValueScan(context.Background(), p, ValueFloat32, "98.54")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var addrs []int64
+	for _, c := range s.Candidates {
+		addrs = append(addrs, c.Addr)
+	}
+
+	if want := []int64{0x1000, 0x1ffc}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("candidates at %x, want %x", addrs, want)
+	}
+}
+
+func TestNextScan(t *testing.T) {
+	newSearch := func(p *fakeProcess) *ValueSearch {
+		s, err := FirstValueScan(context.Background(), p, ValueInt32, "1234")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(s.Candidates) != 3 {
+			t.Fatalf("first scan found %d candidates, want 3", len(s.Candidates))
+		}
+
+		return s
+	}
+
+	p := newFakeProcess(Layout{})
+	p.mapRegion(0x1000, 0x1000)
+	for _, addr := range []int64{0x1000, 0x1008, 0x100c} {
+		p.putUint32(addr, 1234)
+	}
+
+	s := newSearch(p)
+	p.putUint32(0x1008, 1235)
+	p.putUint32(0x100c, 1000)
+
+	tests := []struct {
+		cmp   Comparison
+		value string
+		want  []int64
+	}{
+		{Changed, "", []int64{0x1008, 0x100c}},
+		{Unchanged, "", []int64{0x1000}},
+		{Increased, "", []int64{0x1008}},
+		{Decreased, "", []int64{0x100c}},
+		{Equal, "1235", []int64{0x1008}},
+	}
+
+	for _, tt := range tests {
+		next := &ValueSearch{Type: s.Type, Candidates: append([]Candidate(nil), s.Candidates...)}
+		if err := next.NextScan(context.Background(), p, tt.cmp, tt.value); err != nil {
+			t.Errorf("NextScan(%v): %v", tt.cmp, err)
+			continue
+		}
+
+		var addrs []int64
+		for _, c := range next.Candidates {
+			addrs = append(addrs, c.Addr)
+		}
+
+		if !reflect.DeepEqual(addrs, tt.want) {
+			t.Errorf("NextScan(%v) kept %x, want %x", tt.cmp, addrs, tt.want)
+		}
+	}
+
+	// failed scans leave the candidates as they were
+	before := append([]Candidate(nil), s.Candidates...)
+
+	if err := s.NextScan(context.Background(), p, Equal, "many"); !errors.Is(err, ErrInvalidSearchValue) {
+		t.Errorf("NextScan(Equal, many) = %v, want ErrInvalidSearchValue", err)
+	}
+
+	if err := s.NextScan(context.Background(), p, Comparison(42), ""); !errors.Is(err, ErrInvalidComparison) {
+		t.Errorf("NextScan(42) = %v, want ErrInvalidComparison", err)
+	}
+
+	p.exited = true
+	if err := s.NextScan(context.Background(), p, Unchanged, ""); !errors.Is(err, ErrProcessExited) {
+		t.Errorf("NextScan after exit = %v, want ErrProcessExited", err)
+	}
+
+	if !reflect.DeepEqual(s.Candidates, before) {
+		t.Errorf("failed scans changed the candidates to %+v", s.Candidates)
+	}
+
+	strs := &ValueSearch{Type: ValueString}
+	if err := strs.NextScan(context.Background(), p, Increased, ""); !errors.Is(err, ErrInvalidComparison) {
+		t.Errorf("NextScan(Increased) of strings = %v, want ErrInvalidComparison", err)
+	}
+}
+
+func TestValueSearchFile(t *testing.T) {
+	s := &ValueSearch{Type: ValueString, Candidates: []Candidate{
+		{Addr: 0x1000, Text: "héllo"},
+		{Addr: 0x7fff0000, Raw: 42},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteValueSearch(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadValueSearch(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("ReadValueSearch = %+v, want %+v", got, s)
+	}
+
+	if _, err := ReadValueSearch(bytes.NewReader([]byte("BOSUSNAP"))); !errors.Is(err, ErrNotValueSearch) {
+		t.Errorf("ReadValueSearch(snapshot) = %v, want ErrNotValueSearch", err)
+	}
+
+	if _, err := ReadValueSearch(bytes.NewReader([]byte(valueSearchMagic + "\x07\x00\x00\x00"))); !errors.Is(err, ErrValueSearchVersion) {
+		t.Errorf("ReadValueSearch(version 7) = %v, want ErrValueSearchVersion", err)
+	}
+}
@@ -4,16 +4,21 @@
 package memory
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	windows "github.com/elastic/go-windows"
 	xsyscall "golang.org/x/sys/windows"
 )
 
+// stillActive is the exit code of a process that is still running.
+const stillActive = 259
+
 var (
 	kernel32 = xsyscall.NewLazySystemDLL("kernel32.dll")
 	user32   = xsyscall.NewLazySystemDLL("user32.dll")
@@ -23,6 +28,7 @@ var (
 	getWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
 
 	procVirtualQueryEx         = kernel32.NewProc("VirtualQueryEx")
+	procGetMappedFileNameW     = kernel32.NewProc("K32GetMappedFileNameW")
 	queryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
 )
 
@@ -119,6 +125,23 @@ func virtualQueryEx(handle syscall.Handle, off int64) (region, error) {
 	return reg, nil
 }
 
+func getMappedFileName(handle syscall.Handle, off int64) (string, error) {
+	var buf [syscall.MAX_PATH]uint16
+
+	r1, _, e1 := procGetMappedFileNameW.Call(
+		uintptr(handle),
+		uintptr(off),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+	)
+
+	if r1 == 0 {
+		return "", e1
+	}
+
+	return syscall.UTF16ToString(buf[:r1]), nil
+}
+
 func queryFullProcessImageName(hProcess syscall.Handle) (string, error) {
 	var buf [syscall.MAX_PATH]uint16
 	n := uint32(len(buf))
@@ -214,12 +237,37 @@ func (p process) Close() error {
 	return syscall.CloseHandle(p.h)
 }
 
+func (p process) Alive() bool {
+	var code uint32
+	if err := xsyscall.GetExitCodeProcess(xsyscall.Handle(p.h), &code); err != nil {
+		return false
+	}
+
+	return code == stillActive
+}
+
+func (p process) StartTime() (time.Time, error) {
+	var creation, exit, kernel, user xsyscall.Filetime
+
+	err := xsyscall.GetProcessTimes(xsyscall.Handle(p.h), &creation, &exit, &kernel, &user)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
 func (p process) Pid() int {
 	return int(p.pid)
 }
 
 func (p process) ReadAt(b []byte, off int64) (n int, err error) {
 	un, err := windows.ReadProcessMemory(p.h, uintptr(off), b)
+
+	if errors.Is(err, xsyscall.ERROR_PARTIAL_COPY) || errors.Is(err, xsyscall.ERROR_NOACCESS) {
+		err = fmt.Errorf("reading 0x%x: %w (%w)", off+int64(un), ErrUnmapped, err)
+	}
+
 	return int(un), err
 }
 
@@ -234,7 +282,12 @@ func (p process) Maps() ([]Map, error) {
 			}
 			break
 		}
-		maps = append(maps, reg)
+		m := windowsMap{region: reg}
+		if m.Type() != TypePrivate {
+			m.module, _ = getMappedFileName(p.h, reg.Start())
+		}
+
+		maps = append(maps, m)
 		lastAddr = reg.Start() + reg.Size()
 	}
 	return maps, nil
@@ -257,3 +310,26 @@ func (r region) Start() int64 {
 func (r region) Size() int64 {
 	return int64(r.regionSize)
 }
+
+func (r region) Protection() Protection {
+	return windowsProtection(uint32(r.protect))
+}
+
+func (r region) State() RegionState {
+	return windowsState(uint32(r.state))
+}
+
+func (r region) Type() RegionType {
+	return windowsType(uint32(r.type_))
+}
+
+// windowsMap is a region together with the device path of the file mapped
+// into it, for example \Device\HarddiskVolume3\osu!\osu!.exe.
+type windowsMap struct {
+	region
+	module string
+}
+
+func (m windowsMap) Module() string {
+	return m.module
+}